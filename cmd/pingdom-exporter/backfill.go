@@ -0,0 +1,318 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	backfillFrom   string
+	backfillTo     string
+	backfillStep   time.Duration
+	backfillOutput string
+)
+
+var backfillCommand = &command{
+	name: "backfill",
+	help: "write the status, response time and error budget metrics of the checks within a period of time as an OpenMetrics file, to be imported via `promtool tsdb create-blocks-from openmetrics`",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&backfillFrom, "from", "", "start of the period to backfill, as a RFC 3339 timestamp, a date or a duration ago, i.e. 2024-01-01 or 30d (required)")
+		fs.StringVar(&backfillTo, "to", "", "end of the period to backfill, in the same format as -from (defaults to now)")
+		fs.DurationVar(&backfillStep, "step", time.Minute, "interval between the samples of the status and error budget metrics")
+		fs.StringVar(&backfillOutput, "output", "-", "file to write the OpenMetrics output to, or - for the standard output")
+	},
+	run: runBackfill,
+}
+
+func runBackfill(ctx context.Context, c *commandContext) error {
+	now := c.collector.now()
+	if backfillFrom == "" {
+		return errors.New("the -from flag is required")
+	}
+	from, to, err := parsePeriod(backfillFrom, backfillTo, now, 0)
+	if err != nil {
+		return err
+	}
+	if backfillStep <= 0 {
+		return fmt.Errorf("invalid step %s", backfillStep)
+	}
+
+	selector, err := newSeriesSelectorFromFlags(c.config)
+	if err != nil {
+		return err
+	}
+
+	checks, err := c.collector.exportedChecks()
+	if err != nil {
+		return fmt.Errorf("cannot retrieve checks: %w", err)
+	}
+
+	b := newBackfill(c.collector, from, to, backfillStep)
+	for _, check := range checks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.logger.Info("Backfilling check", "check_id", check.ID, "name", check.Name)
+		if err := b.addCheck(check); err != nil {
+			return fmt.Errorf("cannot backfill check %d: %w", check.ID, err)
+		}
+	}
+
+	return writeOutput(backfillOutput, c.stdout, func(w io.Writer) error {
+		return b.write(w, selector)
+	})
+}
+
+// backfill computes the metrics the exporter would have exported for the
+// checks within a period of time, as timestamped samples:
+//
+//   - the status, from the outage states of the check, every step;
+//   - the response time, from the raw test results of the check, or the
+//     hourly average response time before the oldest available result;
+//   - the up and down time and the error budget within the outage check
+//     period ending at every step, from the outage states of the check.
+type backfill struct {
+	pc       *pingdomCollector
+	from, to time.Time
+	step     time.Duration
+
+	families map[string]*dto.MetricFamily
+	order    []string
+}
+
+func newBackfill(pc *pingdomCollector, from, to time.Time, step time.Duration) *backfill {
+	return &backfill{
+		pc:       pc,
+		from:     from,
+		to:       to,
+		step:     step,
+		families: map[string]*dto.MetricFamily{},
+	}
+}
+
+// addCheck retrieves the outage states, performance summary and raw test
+// results of the check and adds its samples.
+func (b *backfill) addCheck(check pingdom.CheckResponse) error {
+	client := b.pc.client
+	id := strconv.Itoa(check.ID)
+
+	// The error budget at the start of the period depends on the outages
+	// within the preceding outage check period.
+	states, err := client.OutageSummary.List(check.ID, map[string]string{
+		"from": strconv.FormatInt(b.from.Add(-b.pc.outageCheckPeriod).Unix(), 10),
+		"to":   strconv.FormatInt(b.to.Unix(), 10),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot retrieve outages: %w", err)
+	}
+
+	performance, err := client.SummaryPerformance.List(check.ID, map[string]string{
+		"from":       strconv.FormatInt(b.from.Unix(), 10),
+		"to":         strconv.FormatInt(b.to.Unix(), 10),
+		"resolution": "hour",
+	})
+	if err != nil {
+		return fmt.Errorf("cannot retrieve performance summary: %w", err)
+	}
+
+	results, err := listResults(client, check.ID, b.from.Unix(), b.to.Unix())
+	if err != nil {
+		return fmt.Errorf("cannot retrieve results: %w", err)
+	}
+
+	uptimeErrorBudget := b.pc.outageCheckPeriod.Seconds() * (100.0 - b.pc.uptimeSLO(check).Value) / 100.0
+
+	// Samples are aligned to the step, as Prometheus aligns evaluations
+	first := b.from.Truncate(b.step)
+	if first.Before(b.from) {
+		first = first.Add(b.step)
+	}
+
+	for t := first; !t.After(b.to); t = t.Add(b.step) {
+		if status, ok := statusAt(states, t); ok {
+			b.addStatus(check, id, t, status)
+		}
+
+		upTime, downTime := outageTimes(states, t.Add(-b.pc.outageCheckPeriod), t)
+		b.add(pingdomUpTimeMetric, check, t, upTime, id, check.Name, check.Hostname)
+		b.add(pingdomDownTimeMetric, check, t, downTime, id, check.Name, check.Hostname)
+		b.add(pingdomCheckErrorBudgetMetric, check, t, uptimeErrorBudget, id, check.Name, check.Hostname)
+		b.add(pingdomCheckAvailableErrorBudgetMetric, check, t, uptimeErrorBudget-downTime, id, check.Name, check.Hostname)
+	}
+
+	// Results are newest first, so the hourly averages are only used before
+	// the oldest one
+	oldest := b.to.Unix() + 1
+	if len(results) > 0 {
+		oldest = results[len(results)-1].Time
+	}
+	for _, summary := range performance.Hours {
+		t := time.Unix(int64(summary.StartTime), 0)
+		if t.Unix() < oldest && !t.Before(b.from) {
+			b.addResponseTime(check, id, t, states, float64(summary.AvgResponse)/1000.0)
+		}
+	}
+	for i := len(results) - 1; i >= 0; i-- {
+		b.addResponseTime(check, id, time.Unix(results[i].Time, 0), states, float64(results[i].ResponseTime)/1000.0)
+	}
+
+	return nil
+}
+
+// addStatus adds the status samples of the check at the given time, with the
+// layout of labels of the collector.
+func (b *backfill) addStatus(check pingdom.CheckResponse, id string, t time.Time, status string) {
+	var value float64
+	if status == "up" {
+		value = 1
+	}
+
+	if b.pc.stableLabels {
+		for _, s := range checkStates {
+			var inState float64
+			if s == status {
+				inState = 1
+			}
+			b.add(pingdomCheckStateMetric, check, t, inState, id, check.Name, check.Hostname, s)
+		}
+		b.add(pingdomCheckStableStatusMetric, check, t, value, id, check.Name, check.Hostname)
+		return
+	}
+
+	b.add(pingdomCheckStatusMetric, check, t, value, id, check.Name, check.Hostname, status, strconv.Itoa(check.Resolution), "false")
+}
+
+// addResponseTime adds a response time sample of the check, whose status
+// label, without stable labels, is the status of the check at that time.
+func (b *backfill) addResponseTime(check pingdom.CheckResponse, id string, t time.Time, states []pingdom.OutageSummaryResponseState, value float64) {
+	if b.pc.stableLabels {
+		b.add(pingdomCheckStableResponseTimeMetric, check, t, value, id, check.Name, check.Hostname)
+		return
+	}
+
+	status, ok := statusAt(states, t)
+	if !ok {
+		status = "up"
+	}
+	b.add(pingdomCheckResponseTimeMetric, check, t, value, id, check.Name, check.Hostname, status, strconv.Itoa(check.Resolution), "false")
+}
+
+// add adds a sample of the given per-check gauge at the given time, labeled
+// as the collector does.
+func (b *backfill) add(m *checkMetric, check pingdom.CheckResponse, t time.Time, value float64, labelValues ...string) {
+	metric := &dto.Metric{}
+	if err := b.pc.checkMetric(m, check, value, labelValues...).Write(metric); err != nil {
+		return
+	}
+	metric.TimestampMs = proto.Int64(t.UnixMilli())
+
+	family, ok := b.families[m.name]
+	if !ok {
+		family = &dto.MetricFamily{Name: proto.String(m.name), Help: proto.String(m.help), Type: dto.MetricType_GAUGE.Enum()}
+		b.families[m.name] = family
+		b.order = append(b.order, m.name)
+	}
+	family.Metric = append(family.Metric, metric)
+}
+
+// write writes the samples in the OpenMetrics format, without the metric
+// families and labels not selected by the selector. The samples of every
+// series are written together, in time order, and samples of a series with
+// the same timestamp are only written once.
+func (b *backfill) write(w io.Writer, selector *seriesSelector) error {
+	for _, name := range b.order {
+		if !selector.selectedMetric(name) {
+			continue
+		}
+		family := b.families[name]
+
+		type series struct {
+			key     string
+			metrics []*dto.Metric
+		}
+		var all []*series
+		byKey := map[string]*series{}
+
+		for _, metric := range family.Metric {
+			metric.Label = slices.DeleteFunc(metric.Label, func(l *dto.LabelPair) bool {
+				return !selector.selectedLabel(l.GetName())
+			})
+
+			key := labelsKey(metric.Label)
+			s, ok := byKey[key]
+			if !ok {
+				s = &series{key: key}
+				byKey[key] = s
+				all = append(all, s)
+			}
+			s.metrics = append(s.metrics, metric)
+		}
+
+		var metrics []*dto.Metric
+		for _, s := range all {
+			slices.SortStableFunc(s.metrics, func(a, b *dto.Metric) int {
+				return cmp.Compare(a.GetTimestampMs(), b.GetTimestampMs())
+			})
+			s.metrics = slices.CompactFunc(s.metrics, func(a, b *dto.Metric) bool {
+				return a.GetTimestampMs() == b.GetTimestampMs()
+			})
+			metrics = append(metrics, s.metrics...)
+		}
+
+		if _, err := expfmt.MetricFamilyToOpenMetrics(w, &dto.MetricFamily{
+			Name:   family.Name,
+			Help:   family.Help,
+			Type:   family.Type,
+			Metric: metrics,
+		}); err != nil {
+			return err
+		}
+	}
+
+	_, err := expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+// statusAt returns the status of the check at the given time according to
+// its outage states. At the boundary of two states, the later one applies.
+func statusAt(states []pingdom.OutageSummaryResponseState, t time.Time) (status string, ok bool) {
+	ts := t.Unix()
+	for _, state := range states {
+		if state.FromTime <= ts && ts <= state.ToTime {
+			status, ok = state.Status, true
+		}
+	}
+	return status, ok
+}
+
+// outageTimes returns the up and down time of the check within the given
+// period, in seconds, according to its outage states.
+func outageTimes(states []pingdom.OutageSummaryResponseState, from, to time.Time) (upTime, downTime float64) {
+	for _, state := range states {
+		start := max(state.FromTime, from.Unix())
+		end := min(state.ToTime, to.Unix())
+		if end <= start {
+			continue
+		}
+
+		switch state.Status {
+		case "down":
+			downTime += float64(end - start)
+		case "up":
+			upTime += float64(end - start)
+		}
+	}
+	return upTime, downTime
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfill(t *testing.T) {
+	day := 24 * time.Hour
+	outageStart := testNow.Add(-2 * day)
+	ts := func(d time.Duration) int64 {
+		return outageStart.Add(d).Unix()
+	}
+
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			testCheck(1, "api", "up", "uptime_slo_999"),
+			testCheck(2, "ignored", "up", "pingdom_exporter_ignored"),
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: testOutages(10 * time.Minute)},
+		Performance: map[int]pingdom.SummaryPerformanceMap{1: {Hours: []pingdom.SummaryPerformanceSummary{
+			{StartTime: int(ts(-time.Hour)), AvgResponse: 150},
+			{StartTime: int(ts(-4 * time.Minute)), AvgResponse: 180},
+		}}},
+		Results: map[int][]pingdom.ResultsResponseResult{1: {
+			{ProbeID: 63, Time: ts(12 * time.Minute), Status: "up", ResponseTime: 300},
+			{ProbeID: 64, Time: ts(12 * time.Minute), Status: "up", ResponseTime: 320},
+			{ProbeID: 63, Time: ts(-time.Minute), Status: "up", ResponseTime: 200},
+		}},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	selector, err := newSeriesSelector(nil, []string{"pingdom_up_seconds"}, nil, []string{"tags"}, 0)
+	require.NoError(t, err)
+
+	backfillChecks := func(stable bool) string {
+		collector := newPingdomCollector(client, 7*day, 99)
+		collector.stableLabels = stable
+
+		checks, err := collector.exportedChecks()
+		require.NoError(t, err)
+		require.Len(t, checks, 1)
+
+		b := newBackfill(collector, outageStart.Add(-5*time.Minute), outageStart.Add(15*time.Minute), 5*time.Minute)
+		require.NoError(t, b.addCheck(checks[0]))
+
+		var buf bytes.Buffer
+		require.NoError(t, b.write(&buf, selector))
+		return buf.String()
+	}
+
+	path := filepath.Join("testdata", "backfill.om")
+	got := backfillChecks(false)
+	if *update {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), got)
+
+	// With stable labels, the state is backfilled as a state set
+	got = backfillChecks(true)
+	assert.Contains(t, got, `pingdom_check_state{hostname="api.example.com",id="1",name="api",state="down"} 1.0 `+"1.6998273e+09\n")
+	assert.Contains(t, got, `pingdom_uptime_response_time_seconds{hostname="api.example.com",id="1",name="api"} 0.32 `+"1.69982792e+09\n")
+}
+
+func TestParseTime(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"7d", testNow.Add(-7 * 24 * time.Hour)},
+		{"90m", testNow.Add(-90 * time.Minute)},
+	} {
+		got, err := parseTime(tc.value, testNow)
+		require.NoError(t, err, tc.value)
+		assert.True(t, tc.expected.Equal(got), "%s: expected %s, got %s", tc.value, tc.expected, got)
+	}
+
+	for _, value := range []string{"yesterday", "-1d", "1.5d"} {
+		_, err := parseTime(value, testNow)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,1087 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	pingdomUpDesc = prometheus.NewDesc(
+		"pingdom_up",
+		"Whether the last pingdom scrape was successfull (1: up, 0: down).",
+		nil, nil,
+	)
+
+	pingdomRateLimitRemainingRequestsDesc = prometheus.NewDesc(
+		"pingdom_rate_limit_remaining_requests",
+		"Tracks the remaining requests allowed before hitting the short-term or long-term rate limit in the Pingdom API.",
+		nil, nil,
+	)
+
+	pingdomAuthFailedDesc = prometheus.NewDesc(
+		"pingdom_auth_failed",
+		"Whether the Pingdom API rejected the API token on the last request (1: rejected, 0: accepted)",
+		nil, nil,
+	)
+
+	pingdomOutageCheckPeriodDesc = prometheus.NewDesc(
+		"pingdom_slo_period_seconds",
+		"Outage check period, in seconds",
+		nil, nil,
+	)
+
+	pingdomCheckScrapeSuccessDesc = prometheus.NewDesc(
+		"pingdom_check_scrape_success",
+		"Whether the outage data of the check was successfully retrieved from the Pingdom API (1: success, 0: failure)",
+		[]string{"id"}, nil,
+	)
+
+	pingdomCheckOutageDataAgeDesc = prometheus.NewDesc(
+		"pingdom_check_outage_data_age_seconds",
+		"Age of the outage data exported for the check, in seconds; greater than zero when serving stale data after an error",
+		[]string{"id"}, nil,
+	)
+
+	pingdomServiceChecksDesc = prometheus.NewDesc(
+		"pingdom_service_checks",
+		"Number of exported checks which are part of the service",
+		[]string{"service"}, nil,
+	)
+
+	pingdomServiceDownTimeDesc = prometheus.NewDesc(
+		"pingdom_service_down_seconds",
+		"Total down time of the service within the outage check period, in seconds, merging the outages of its checks according to its mode",
+		[]string{"service"}, nil,
+	)
+
+	pingdomServiceAvailabilityDesc = prometheus.NewDesc(
+		"pingdom_service_availability_ratio",
+		"Availability of the service within the outage check period, as a ratio",
+		[]string{"service"}, nil,
+	)
+
+	pingdomServiceErrorBudgetDesc = prometheus.NewDesc(
+		"pingdom_service_uptime_slo_error_budget_total_seconds",
+		"Maximum number of allowed downtime of the service, in seconds, according to its uptime SLO",
+		[]string{"service"}, nil,
+	)
+
+	pingdomServiceAvailableErrorBudgetDesc = prometheus.NewDesc(
+		"pingdom_service_uptime_slo_error_budget_available_seconds",
+		"Number of seconds of downtime the service can still have without breaking its uptime SLO",
+		[]string{"service"}, nil,
+	)
+)
+
+// checkMetric is a metric exported for every check. Besides the given labels,
+// it has the labels derived from the tags of the check, see tagLabeler.
+type checkMetric struct {
+	name   string
+	help   string
+	labels []string
+}
+
+var (
+	pingdomCheckStatusMetric = &checkMetric{
+		"pingdom_uptime_status",
+		"The current status of the check (1: up, 0: down)",
+		[]string{"id", "name", "hostname", "status", "resolution", "paused"},
+	}
+
+	pingdomCheckResponseTimeMetric = &checkMetric{
+		"pingdom_uptime_response_time_seconds",
+		"The response time of last test, in seconds",
+		[]string{"id", "name", "hostname", "status", "resolution", "paused"},
+	}
+
+	pingdomOutagesMetric = &checkMetric{
+		"pingdom_outages_total",
+		"Number of outages of the check since the beginning of the outage check period when the exporter started",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomCheckResultResponseTimeMetric = &checkMetric{
+		"pingdom_check_response_time_seconds",
+		"Response time of the successful test results of the check, in seconds",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomCheckErrorBudgetMetric = &checkMetric{
+		"pingdom_uptime_slo_error_budget_total_seconds",
+		"Maximum number of allowed downtime, in seconds, according to the uptime SLO",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomCheckAvailableErrorBudgetMetric = &checkMetric{
+		"pingdom_uptime_slo_error_budget_available_seconds",
+		"Number of seconds of downtime we can still have without breaking the uptime SLO",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomDownTimeMetric = &checkMetric{
+		"pingdom_down_seconds",
+		"Total down time within the outage check period, in seconds",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomUpTimeMetric = &checkMetric{
+		"pingdom_up_seconds",
+		"Total up time within the outage check period, in seconds",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomCheckUptimeSLOMetric = &checkMetric{
+		"pingdom_check_uptime_slo_ratio",
+		"Uptime SLO of the check, as a ratio, configured either via a tag or the default uptime SLO",
+		[]string{"id", "name", "hostname", "source"},
+	}
+
+	pingdomCheckSLOConfigErrorMetric = &checkMetric{
+		"pingdom_check_slo_config_error",
+		"Whether the check has SLO tags which are invalid for the given reason (unparseable, out_of_range, conflicting) and ignored; only exported when they are",
+		[]string{"id", "name", "hostname", "reason"},
+	}
+
+	pingdomCheckProjectedErrorBudgetMetric = &checkMetric{
+		"pingdom_uptime_slo_error_budget_projected_available_seconds",
+		"Number of seconds of downtime forecast to be left once the outages within the outage check period have rolled out of it, given the burn rate of the forecast window",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomCheckErrorBudgetExhaustionMetric = &checkMetric{
+		"pingdom_uptime_slo_error_budget_exhaustion_timestamp_seconds",
+		"Unix timestamp the error budget is forecast to be exhausted at, given the burn rate of the forecast window; only exported when it is within the outage check period",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomLatencySLOGoodEventsMetric = &checkMetric{
+		"pingdom_latency_slo_good_events",
+		"Number of successful tests of the check within the outage check period which responded within the threshold of the latency SLO",
+		[]string{"id", "name", "hostname", "objective", "threshold"},
+	}
+
+	pingdomLatencySLOTotalEventsMetric = &checkMetric{
+		"pingdom_latency_slo_total_events",
+		"Number of successful tests of the check within the outage check period, against which the latency SLO is evaluated",
+		[]string{"id", "name", "hostname", "objective", "threshold"},
+	}
+
+	pingdomLatencySLOErrorBudgetRemainingMetric = &checkMetric{
+		"pingdom_latency_slo_error_budget_remaining_ratio",
+		"Ratio of the tests allowed to respond slower than the threshold of the latency SLO within the outage check period which are left; negative once the latency SLO is broken",
+		[]string{"id", "name", "hostname", "objective", "threshold"},
+	}
+
+	pingdomSLIGoodEventsMetric = &checkMetric{
+		"pingdom_sli_good_events_total",
+		"Number of successful tests of the check since the beginning of the outage check period when the exporter started",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomSLITotalEventsMetric = &checkMetric{
+		"pingdom_sli_total_events_total",
+		"Number of successful and failed tests of the check since the beginning of the outage check period when the exporter started",
+		[]string{"id", "name", "hostname"},
+	}
+
+	// Metrics exported with stable labels, see pingdomCollector.stableLabels.
+
+	pingdomCheckStateMetric = &checkMetric{
+		"pingdom_check_state",
+		"Whether the check is in the given state (1: in state, 0: not in state)",
+		[]string{"id", "name", "hostname", "state"},
+	}
+
+	pingdomCheckStableStatusMetric = &checkMetric{
+		"pingdom_uptime_status",
+		"The current status of the check (1: up, 0: down)",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomCheckStableResponseTimeMetric = &checkMetric{
+		"pingdom_uptime_response_time_seconds",
+		"The response time of last test, in seconds",
+		[]string{"id", "name", "hostname"},
+	}
+
+	pingdomCheckResolutionMetric = &checkMetric{
+		"pingdom_check_resolution_seconds",
+		"How often the check is tested (Pingdom resolution), in seconds",
+		[]string{"id", "name", "hostname"},
+	}
+
+	checkMetrics = []*checkMetric{
+		pingdomCheckStatusMetric,
+		pingdomCheckResponseTimeMetric,
+		pingdomCheckStateMetric,
+		pingdomCheckStableStatusMetric,
+		pingdomCheckStableResponseTimeMetric,
+		pingdomCheckResolutionMetric,
+		pingdomCheckResultResponseTimeMetric,
+		pingdomOutagesMetric,
+		pingdomCheckErrorBudgetMetric,
+		pingdomCheckAvailableErrorBudgetMetric,
+		pingdomDownTimeMetric,
+		pingdomUpTimeMetric,
+		pingdomCheckProjectedErrorBudgetMetric,
+		pingdomCheckErrorBudgetExhaustionMetric,
+		pingdomCheckUptimeSLOMetric,
+		pingdomCheckSLOConfigErrorMetric,
+		pingdomLatencySLOGoodEventsMetric,
+		pingdomLatencySLOTotalEventsMetric,
+		pingdomLatencySLOErrorBudgetRemainingMetric,
+		pingdomSLIGoodEventsMetric,
+		pingdomSLITotalEventsMetric,
+	}
+
+	// checkMetricLabels are the labels set by the exporter on the per-check
+	// metrics, which cannot be derived from tags.
+	checkMetricLabels = []string{"id", "name", "hostname", "status", "resolution", "paused", "state", "source", "reason", "objective", "threshold"}
+
+	// checkStates are the states of a check exported by pingdom_check_state.
+	checkStates = []string{"up", "down", "unconfirmed_down", "unknown", "paused"}
+)
+
+// Endpoint templates used to report Pingdom API errors.
+const (
+	checksEndpoint        = "/checks"
+	outageSummaryEndpoint = "/summary.outage/{id}"
+)
+
+// defaultMinUptimeSLO is the minimum uptime SLO of a valid uptime SLO tag by
+// default, so i.e. uptime_slo_9 is reported as out of range rather than taken
+// as a 9% uptime SLO.
+const defaultMinUptimeSLO = 90.0
+
+// readyCheckInterval is the minimum interval between the requests made to
+// the Pingdom API by Ready, so frequent readiness probes don't consume the
+// rate limit while the API is unavailable.
+const readyCheckInterval = 10 * time.Second
+
+// outageSummary holds the outage data of a check within the outage check
+// period, as retrieved from the Pingdom API at a given point in time.
+type outageSummary struct {
+	upTime    float64
+	downTime  float64
+	fetchedAt time.Time
+
+	// Outages of the check, to merge them with the ones of the other checks
+	// of a service.
+	downIntervals []interval
+
+	// Number of outages counted since outagesCreated, see outageCounter.
+	outages        float64
+	outagesCreated time.Time
+}
+
+// outageCounter counts the outages of a check seen by the exporter, so they
+// are exported as a proper counter. It starts with the outages within the
+// outage check period when the check is first seen, counting since the
+// beginning of that period.
+type outageCounter struct {
+	created time.Time
+	count   float64
+
+	// Start time of the last counted outage.
+	lastFrom int64
+}
+
+type pingdomCollector struct {
+	client *pingdom.Client
+
+	// Time window in which to retrieve outage data from the Pingdom API.
+	outageCheckPeriod time.Duration
+
+	// Uptime SLO used when the check doesn't provide a valid uptime SLO tag.
+	defaultUptimeSLO float64
+
+	// Minimum uptime SLO of a valid uptime SLO tag, if positive.
+	minUptimeSLO float64
+
+	// Recent period the burn rate of the error budget is computed over, to
+	// forecast it.
+	forecastWindow time.Duration
+
+	// Maximum age of the last known good outage data to be exported when the
+	// Pingdom API fails to return the outage data of a check. Zero disables
+	// serving stale data.
+	staleOutageMaxAge time.Duration
+
+	// Selects the checks to be exported; all checks when nil.
+	filter *checkFilter
+
+	// Derives labels of the per-check metrics from the tags of the checks.
+	tagLabels *tagLabeler
+
+	// Composite services made of the exported checks.
+	services []*service
+
+	// Exports the status of the checks via pingdom_check_state, without the
+	// status, paused and resolution labels, so the series of a check don't
+	// change on status transitions.
+	stableLabels bool
+
+	// Retrieves the raw test results of the checks to export the response
+	// time histogram, whose exemplars link to resultURLTemplate.
+	collectResults    bool
+	resultURLTemplate string
+
+	// Retrieves the raw test results of the checks to count their good and
+	// total events, see eventCounter.
+	collectEvents bool
+
+	// Timestamps the status and response time of the checks with the time
+	// of their last test, as when pushing them to a remote write endpoint.
+	lastTestTimestamps bool
+
+	descsOnce sync.Once
+	descs     map[*checkMetric]*prometheus.Desc
+
+	logger *slog.Logger
+
+	// now returns the current time, overridden by tests.
+	now func() time.Time
+
+	apiErrors *prometheus.CounterVec
+
+	// ready is set once checks were successfully retrieved from Pingdom.
+	ready atomic.Bool
+
+	// readyCheckedAt is when Ready last requested the Pingdom API.
+	readyMu        sync.Mutex
+	readyCheckedAt time.Time
+
+	mu             sync.Mutex
+	lastOutages    map[int]outageSummary
+	outageCounters map[int]*outageCounter
+	results        map[int]*resultHistory
+	latencies      map[int]*latencyWindow
+	events         map[int]*eventCounter
+}
+
+// newPingdomCollector returns a collector that exports the checks and outages
+// retrieved via the given Pingdom client.
+func newPingdomCollector(client *pingdom.Client, outageCheckPeriod time.Duration, defaultUptimeSLO float64) *pingdomCollector {
+	return &pingdomCollector{
+		client:            client,
+		outageCheckPeriod: outageCheckPeriod,
+		defaultUptimeSLO:  defaultUptimeSLO,
+		minUptimeSLO:      defaultMinUptimeSLO,
+		forecastWindow:    defaultForecastWindow,
+		logger:            slog.Default(),
+		now:               time.Now,
+		apiErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pingdom_api_errors_total",
+			Help: "Number of failed requests to the Pingdom API, by endpoint and HTTP status code (none: no response received)",
+		}, []string{"endpoint", "code"}),
+		resultURLTemplate: defaultResultURLTemplate,
+		lastOutages:       map[int]outageSummary{},
+		outageCounters:    map[int]*outageCounter{},
+		results:           map[int]*resultHistory{},
+		latencies:         map[int]*latencyWindow{},
+		events:            map[int]*eventCounter{},
+	}
+}
+
+func (pc *pingdomCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pingdomUpDesc
+	ch <- pingdomRateLimitRemainingRequestsDesc
+	ch <- pingdomAuthFailedDesc
+	ch <- pingdomOutageCheckPeriodDesc
+	for _, m := range pc.checkMetrics() {
+		ch <- pc.desc(m)
+	}
+	ch <- pingdomCheckScrapeSuccessDesc
+	ch <- pingdomCheckOutageDataAgeDesc
+	ch <- pingdomServiceChecksDesc
+	ch <- pingdomServiceDownTimeDesc
+	ch <- pingdomServiceAvailabilityDesc
+	ch <- pingdomServiceErrorBudgetDesc
+	ch <- pingdomServiceAvailableErrorBudgetDesc
+	pc.apiErrors.Describe(ch)
+}
+
+func (pc *pingdomCollector) Collect(ch chan<- prometheus.Metric) {
+	defer pc.apiErrors.Collect(ch)
+
+	outageCheckPeriodSecs := float64(pc.outageCheckPeriod / time.Second)
+
+	checks, minReqLimit, err := pc.client.Checks.List(map[string]string{
+		"include_tags":     "true",
+		"include_severity": "true",
+		"tags":             pc.client.Tags,
+	})
+
+	ch <- prometheus.MustNewConstMetric(
+		pingdomRateLimitRemainingRequestsDesc,
+		prometheus.GaugeValue,
+		minReqLimit,
+	)
+
+	var authFailed float64
+	if pc.client.AuthFailed() {
+		authFailed = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		pingdomAuthFailedDesc,
+		prometheus.GaugeValue,
+		authFailed,
+	)
+
+	if err != nil {
+		pc.logger.Error("Error getting checks", "err", err)
+		pc.countAPIError(checksEndpoint, err)
+		ch <- prometheus.MustNewConstMetric(
+			pingdomUpDesc,
+			prometheus.GaugeValue,
+			float64(0),
+		)
+		return
+	}
+
+	pc.ready.Store(true)
+
+	ch <- prometheus.MustNewConstMetric(
+		pingdomUpDesc,
+		prometheus.GaugeValue,
+		float64(1),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		pingdomOutageCheckPeriodDesc,
+		prometheus.GaugeValue,
+		outageCheckPeriodSecs,
+	)
+
+	var (
+		wg sync.WaitGroup
+
+		// Outage data exported per check, to compute the services from
+		summariesMu sync.Mutex
+		summaries   = map[int]outageSummary{}
+	)
+	seen := map[int]bool{}
+
+	for _, check := range checks {
+
+		if !pc.exports(check) {
+			continue
+		}
+
+		seen[check.ID] = true
+		id := strconv.Itoa(check.ID)
+
+		if pc.stableLabels {
+			pc.collectCheckState(ch, check, id)
+		} else {
+			pc.collectCheckStatus(ch, check, id)
+		}
+
+		uptimeSLO := pc.uptimeSLO(check)
+		latencySLOs, latencySLOErrors := check.LatencySLOs()
+		pc.collectSLOConfig(ch, check, id, uptimeSLO, latencySLOErrors)
+
+		// Maximum allowed downtime, in seconds, according to the uptime SLO
+		uptimeErrorBudget := outageCheckPeriodSecs * (100.0 - uptimeSLO.Value) / 100.0
+
+		// Retrieve the outage list within the desired period for this check, in background
+		wg.Add(1)
+
+		go func(check pingdom.CheckResponse) {
+			defer wg.Done()
+
+			if pc.collectResults || pc.collectEvents || len(latencySLOs) > 0 {
+				pc.collectCheckResults(ch, check, id, latencySLOs)
+			}
+
+			summary, err := pc.fetchOutageSummary(check.ID)
+
+			var scrapeSuccess float64
+			if err == nil {
+				scrapeSuccess = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				pingdomCheckScrapeSuccessDesc,
+				prometheus.GaugeValue,
+				scrapeSuccess,
+				id,
+			)
+
+			if err != nil {
+				pc.logger.Error("Error getting outages", "check_id", check.ID, "err", err)
+				pc.countAPIError(outageSummaryEndpoint, err)
+
+				var ok bool
+				if summary, ok = pc.staleOutageSummary(check.ID); !ok {
+					return
+				}
+				pc.logger.Warn("Serving stale outage data", "check_id", check.ID, "fetched_at", summary.fetchedAt)
+			}
+
+			summariesMu.Lock()
+			summaries[check.ID] = summary
+			summariesMu.Unlock()
+
+			ch <- prometheus.MustNewConstMetric(
+				pingdomCheckOutageDataAgeDesc,
+				prometheus.GaugeValue,
+				pc.now().Sub(summary.fetchedAt).Seconds(),
+				id,
+			)
+
+			ch <- prometheus.MustNewConstMetricWithCreatedTimestamp(
+				pc.desc(pingdomOutagesMetric),
+				prometheus.CounterValue,
+				summary.outages,
+				summary.outagesCreated,
+				pc.checkLabelValues(check, id, check.Name, check.Hostname)...,
+			)
+
+			ch <- pc.checkMetric(
+				pingdomUpTimeMetric,
+				check,
+				summary.upTime,
+				id,
+				check.Name,
+				check.Hostname,
+			)
+
+			ch <- pc.checkMetric(
+				pingdomDownTimeMetric,
+				check,
+				summary.downTime,
+				id,
+				check.Name,
+				check.Hostname,
+			)
+
+			ch <- pc.checkMetric(
+				pingdomCheckErrorBudgetMetric,
+				check,
+				uptimeErrorBudget,
+				id,
+				check.Name,
+				check.Hostname,
+			)
+
+			ch <- pc.checkMetric(
+				pingdomCheckAvailableErrorBudgetMetric,
+				check,
+				uptimeErrorBudget-summary.downTime,
+				id,
+				check.Name,
+				check.Hostname,
+			)
+
+			// Forecast as of the time the outage data was retrieved, which
+			// is not now when serving stale data
+			forecast := forecastErrorBudget(summary.downIntervals, summary.fetchedAt, pc.outageCheckPeriod, pc.forecastWindow, uptimeErrorBudget)
+
+			ch <- pc.checkMetric(
+				pingdomCheckProjectedErrorBudgetMetric,
+				check,
+				forecast.projectedAvailable,
+				id,
+				check.Name,
+				check.Hostname,
+			)
+
+			if !forecast.exhaustion.IsZero() {
+				ch <- pc.checkMetric(
+					pingdomCheckErrorBudgetExhaustionMetric,
+					check,
+					float64(forecast.exhaustion.UnixMilli())/1000,
+					id,
+					check.Name,
+					check.Hostname,
+				)
+			}
+		}(check)
+	}
+
+	wg.Wait()
+
+	pc.collectServices(ch, checks, seen, summaries)
+
+	// Forget the outage data and results of checks that no longer exist
+	pc.mu.Lock()
+	for id := range pc.lastOutages {
+		if !seen[id] {
+			delete(pc.lastOutages, id)
+		}
+	}
+	for id := range pc.outageCounters {
+		if !seen[id] {
+			delete(pc.outageCounters, id)
+		}
+	}
+	for id := range pc.results {
+		if !seen[id] {
+			delete(pc.results, id)
+		}
+	}
+	for id := range pc.latencies {
+		if !seen[id] {
+			delete(pc.latencies, id)
+		}
+	}
+	for id := range pc.events {
+		if !seen[id] {
+			delete(pc.events, id)
+		}
+	}
+	pc.mu.Unlock()
+}
+
+// collectServices exports the availability and error budget of the services,
+// from the outage data of their exported checks. Services are only exported
+// when the outage data of all of their checks is available.
+func (pc *pingdomCollector) collectServices(ch chan<- prometheus.Metric, checks []pingdom.CheckResponse, exported map[int]bool, summaries map[int]outageSummary) {
+	period := pc.outageCheckPeriod.Seconds()
+
+	for _, s := range pc.services {
+		var (
+			members       int
+			downIntervals = map[int][]interval{}
+		)
+		for _, check := range checks {
+			if !exported[check.ID] || !s.Match(check) {
+				continue
+			}
+			members++
+			if summary, ok := summaries[check.ID]; ok {
+				downIntervals[check.ID] = summary.downIntervals
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(pingdomServiceChecksDesc, prometheus.GaugeValue, float64(members), s.name)
+
+		if members == 0 {
+			continue
+		}
+		if len(downIntervals) < members {
+			pc.logger.Warn("Missing outage data of checks of service", "service", s.name, "missing", members-len(downIntervals))
+			continue
+		}
+
+		downTime := s.downTime(downIntervals)
+		errorBudget := period * (100.0 - s.uptimeSLO) / 100.0
+
+		ch <- prometheus.MustNewConstMetric(pingdomServiceDownTimeDesc, prometheus.GaugeValue, downTime, s.name)
+		ch <- prometheus.MustNewConstMetric(pingdomServiceAvailabilityDesc, prometheus.GaugeValue, 1-downTime/period, s.name)
+		ch <- prometheus.MustNewConstMetric(pingdomServiceErrorBudgetDesc, prometheus.GaugeValue, errorBudget, s.name)
+		ch <- prometheus.MustNewConstMetric(pingdomServiceAvailableErrorBudgetDesc, prometheus.GaugeValue, errorBudget-downTime, s.name)
+	}
+}
+
+// exports returns true if the check is exported, i.e. it doesn't have the
+// ignore tag and matches the filter.
+func (pc *pingdomCollector) exports(check pingdom.CheckResponse) bool {
+	return !check.HasIgnoreTag() && pc.filter.Match(check)
+}
+
+// exportedChecks retrieves the checks exported by the collector.
+func (pc *pingdomCollector) exportedChecks() ([]pingdom.CheckResponse, error) {
+	checks, err := pc.matchingChecks()
+	if err != nil {
+		return nil, err
+	}
+
+	var exported []pingdom.CheckResponse
+	for _, check := range checks {
+		if !check.HasIgnoreTag() {
+			exported = append(exported, check)
+		}
+	}
+	return exported, nil
+}
+
+// matchingChecks retrieves the checks matching the filter of the collector,
+// including the ones with the ignore tag.
+func (pc *pingdomCollector) matchingChecks() ([]pingdom.CheckResponse, error) {
+	checks, _, err := pc.client.Checks.List(map[string]string{
+		"include_tags":     "true",
+		"include_severity": "true",
+		"tags":             pc.client.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []pingdom.CheckResponse
+	for _, check := range checks {
+		if pc.filter.Match(check) {
+			matching = append(matching, check)
+		}
+	}
+	return matching, nil
+}
+
+// checkMetrics returns the per-check metrics exported by the collector.
+func (pc *pingdomCollector) checkMetrics() []*checkMetric {
+	var status []*checkMetric
+	if pc.stableLabels {
+		status = []*checkMetric{
+			pingdomCheckStateMetric,
+			pingdomCheckStableStatusMetric,
+			pingdomCheckStableResponseTimeMetric,
+			pingdomCheckResolutionMetric,
+		}
+	} else {
+		status = []*checkMetric{
+			pingdomCheckStatusMetric,
+			pingdomCheckResponseTimeMetric,
+		}
+	}
+
+	if pc.collectResults {
+		status = append(status, pingdomCheckResultResponseTimeMetric)
+	}
+	if pc.collectEvents {
+		status = append(status, pingdomSLIGoodEventsMetric, pingdomSLITotalEventsMetric)
+	}
+
+	return append(status,
+		pingdomOutagesMetric,
+		pingdomCheckErrorBudgetMetric,
+		pingdomCheckAvailableErrorBudgetMetric,
+		pingdomDownTimeMetric,
+		pingdomUpTimeMetric,
+		pingdomCheckProjectedErrorBudgetMetric,
+		pingdomCheckErrorBudgetExhaustionMetric,
+		pingdomCheckUptimeSLOMetric,
+		pingdomCheckSLOConfigErrorMetric,
+		pingdomLatencySLOGoodEventsMetric,
+		pingdomLatencySLOTotalEventsMetric,
+		pingdomLatencySLOErrorBudgetRemainingMetric,
+	)
+}
+
+// uptimeSLO returns the uptime SLO of the check, as configured via its tags.
+func (pc *pingdomCollector) uptimeSLO(check pingdom.CheckResponse) pingdom.UptimeSLO {
+	return check.UptimeSLO(pc.defaultUptimeSLO, pc.minUptimeSLO)
+}
+
+// collectSLOConfig exports the uptime SLO of the check and, once per reason,
+// the errors of its uptime and latency SLO tags.
+func (pc *pingdomCollector) collectSLOConfig(ch chan<- prometheus.Metric, check pingdom.CheckResponse, id string, uptimeSLO pingdom.UptimeSLO, latencySLOErrors []pingdom.SLOTagError) {
+	ch <- pc.checkMetric(pingdomCheckUptimeSLOMetric, check, percentToRatio(uptimeSLO.Value), id, check.Name, check.Hostname, uptimeSLO.Source)
+
+	var reasons []string
+	for _, err := range slices.Concat(uptimeSLO.Errors, latencySLOErrors) {
+		if !slices.Contains(reasons, err.Reason) {
+			reasons = append(reasons, err.Reason)
+			ch <- pc.checkMetric(pingdomCheckSLOConfigErrorMetric, check, 1, id, check.Name, check.Hostname, err.Reason)
+		}
+	}
+}
+
+// percentToRatio converts a percentage to a ratio by shifting its decimal
+// digits, so i.e. 99.9 is 0.999 rather than 0.9990000000000001.
+func percentToRatio(percent float64) float64 {
+	ratio, err := strconv.ParseFloat(strconv.FormatFloat(percent, 'g', -1, 64)+"e-2", 64)
+	if err != nil {
+		return percent / 100.0
+	}
+	return ratio
+}
+
+// collectCheckStatus exports the status and response time of the check with
+// the status, resolution and paused labels.
+func (pc *pingdomCollector) collectCheckStatus(ch chan<- prometheus.Metric, check pingdom.CheckResponse, id string) {
+	resolution := strconv.Itoa(check.Resolution)
+
+	var status float64
+	paused := "false"
+	if check.Status == "paused" {
+		paused = "true"
+	} else if check.Status == "up" {
+		status = 1
+	}
+
+	ch <- pc.lastTestMetric(
+		pingdomCheckStatusMetric,
+		check,
+		status,
+		id,
+		check.Name,
+		check.Hostname,
+		check.Status,
+		resolution,
+		paused,
+	)
+
+	ch <- pc.lastTestMetric(
+		pingdomCheckResponseTimeMetric,
+		check,
+		float64(check.LastResponseTime)/1000.0,
+		id,
+		check.Name,
+		check.Hostname,
+		check.Status,
+		resolution,
+		paused,
+	)
+}
+
+// collectCheckState exports the status and response time of the check with
+// stable labels, along with its state as a state set.
+func (pc *pingdomCollector) collectCheckState(ch chan<- prometheus.Metric, check pingdom.CheckResponse, id string) {
+	state := check.Status
+	if !slices.Contains(checkStates, state) {
+		state = "unknown"
+	}
+
+	for _, s := range checkStates {
+		var value float64
+		if s == state {
+			value = 1
+		}
+		ch <- pc.lastTestMetric(pingdomCheckStateMetric, check, value, id, check.Name, check.Hostname, s)
+	}
+
+	var status float64
+	if state == "up" {
+		status = 1
+	}
+
+	ch <- pc.lastTestMetric(pingdomCheckStableStatusMetric, check, status, id, check.Name, check.Hostname)
+	ch <- pc.lastTestMetric(pingdomCheckStableResponseTimeMetric, check, float64(check.LastResponseTime)/1000.0, id, check.Name, check.Hostname)
+	ch <- pc.lastTestMetric(pingdomCheckResolutionMetric, check, float64(check.Resolution*60), id, check.Name, check.Hostname)
+}
+
+// desc returns the descriptor of the given per-check metric, whose labels
+// depend on the tag labels of the collector.
+func (pc *pingdomCollector) desc(m *checkMetric) *prometheus.Desc {
+	pc.descsOnce.Do(func() {
+		pc.descs = map[*checkMetric]*prometheus.Desc{}
+		for _, m := range checkMetrics {
+			labels := append(slices.Clone(m.labels), pc.tagLabels.labelNames()...)
+			pc.descs[m] = prometheus.NewDesc(m.name, m.help, labels, nil)
+		}
+	})
+	return pc.descs[m]
+}
+
+// checkMetric returns a sample of the given per-check gauge, with the given
+// label values followed by the ones derived from the tags of the check.
+func (pc *pingdomCollector) checkMetric(m *checkMetric, check pingdom.CheckResponse, value float64, labelValues ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(pc.desc(m), prometheus.GaugeValue, value, pc.checkLabelValues(check, labelValues...)...)
+}
+
+// lastTestMetric returns a sample of the given per-check gauge like
+// checkMetric, timestamped with the time of the last test of the check when
+// lastTestTimestamps is set.
+func (pc *pingdomCollector) lastTestMetric(m *checkMetric, check pingdom.CheckResponse, value float64, labelValues ...string) prometheus.Metric {
+	metric := pc.checkMetric(m, check, value, labelValues...)
+	if !pc.lastTestTimestamps || check.LastTestTime == 0 {
+		return metric
+	}
+	return prometheus.NewMetricWithTimestamp(time.Unix(check.LastTestTime, 0), metric)
+}
+
+// checkLabelValues returns the given label values followed by the ones
+// derived from the tags of the check.
+func (pc *pingdomCollector) checkLabelValues(check pingdom.CheckResponse, labelValues ...string) []string {
+	return append(labelValues, pc.tagLabels.labelValues(check)...)
+}
+
+// Ready reports whether checks were successfully retrieved from Pingdom at
+// least once. Until then, a single check is requested from the Pingdom API at
+// most every readyCheckInterval, so the exporter becomes ready without
+// waiting for a scrape.
+func (pc *pingdomCollector) Ready() bool {
+	if pc.ready.Load() {
+		return true
+	}
+
+	pc.readyMu.Lock()
+	now := pc.now()
+	if now.Sub(pc.readyCheckedAt) < readyCheckInterval {
+		pc.readyMu.Unlock()
+		return false
+	}
+	pc.readyCheckedAt = now
+	pc.readyMu.Unlock()
+
+	if _, _, err := pc.client.Checks.List(map[string]string{"limit": "1"}); err != nil {
+		pc.logger.Debug("Not ready yet", "err", err)
+		return false
+	}
+
+	pc.ready.Store(true)
+	return true
+}
+
+// fetchOutageSummary retrieves the outages of the given check within the
+// outage check period, keeping the result as the last known good outage data
+// for the check.
+func (pc *pingdomCollector) fetchOutageSummary(checkID int) (outageSummary, error) {
+	now := pc.now()
+	from := now.Add(-pc.outageCheckPeriod).Unix()
+	states, err := pc.client.OutageSummary.List(checkID, map[string]string{
+		"from": strconv.FormatInt(from, 10),
+		"to":   strconv.FormatInt(now.Unix(), 10),
+	})
+
+	if err != nil {
+		return outageSummary{}, err
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	counter, seen := pc.outageCounters[checkID]
+	if !seen {
+		counter = &outageCounter{created: now.Add(-pc.outageCheckPeriod)}
+		pc.outageCounters[checkID] = counter
+	}
+
+	summary := outageSummary{fetchedAt: now}
+	for _, state := range states {
+		switch state.Status {
+		case "down":
+			summary.downTime = summary.downTime + float64(state.ToTime-state.FromTime)
+			summary.downIntervals = append(summary.downIntervals, interval{state.FromTime, state.ToTime})
+			// The Pingdom API clips the states to the period, so a down state
+			// starting at its beginning started before it, and was counted
+			// already unless the check is seen for the first time
+			if state.FromTime > counter.lastFrom && (!seen || state.FromTime > from) {
+				counter.count++
+				counter.lastFrom = state.FromTime
+			}
+		case "up":
+			summary.upTime = summary.upTime + float64(state.ToTime-state.FromTime)
+		}
+	}
+
+	summary.outages = counter.count
+	summary.outagesCreated = counter.created
+	pc.lastOutages[checkID] = summary
+
+	return summary, nil
+}
+
+// collectCheckResults retrieves the raw test results of the check since the
+// last ones retrieved, to export the response time histogram and the SLI
+// event counters when enabled, and to evaluate the latency SLOs of the check,
+// if any. The results within the whole outage check period are retrieved the
+// first time the check has latency SLOs or its events are counted.
+func (pc *pingdomCollector) collectCheckResults(ch chan<- prometheus.Metric, check pingdom.CheckResponse, id string, latencySLOs []pingdom.LatencySLO) {
+	now := pc.now()
+	from := now.Unix()
+
+	var (
+		history *resultHistory
+		window  *latencyWindow
+		events  *eventCounter
+	)
+
+	pc.mu.Lock()
+	if pc.collectResults {
+		var ok bool
+		if history, ok = pc.results[check.ID]; !ok {
+			history = newResultHistory(now.Add(-resultsInitialWindow))
+			pc.results[check.ID] = history
+		}
+		from = min(from, history.cursor.time)
+	}
+	if len(latencySLOs) > 0 {
+		var ok bool
+		if window, ok = pc.latencies[check.ID]; !ok {
+			window = newLatencyWindow(now.Add(-pc.outageCheckPeriod))
+			pc.latencies[check.ID] = window
+		}
+		from = min(from, window.cursor.time)
+	}
+	if pc.collectEvents {
+		var ok bool
+		if events, ok = pc.events[check.ID]; !ok {
+			events = newEventCounter(now.Add(-pc.outageCheckPeriod))
+			pc.events[check.ID] = events
+		}
+		from = min(from, events.cursor.time)
+	}
+	pc.mu.Unlock()
+
+	results, err := listResults(pc.client, check.ID, from, now.Unix())
+	if err != nil {
+		pc.logger.Error("Error getting results", "check_id", check.ID, "err", err)
+		pc.countAPIError(resultsEndpoint, err)
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if history != nil {
+		history.observe(check.ID, results, pc.resultURLTemplate)
+		m, err := history.metric(pc.desc(pingdomCheckResultResponseTimeMetric), pc.checkLabelValues(check, id, check.Name, check.Hostname)...)
+		if err != nil {
+			pc.logger.Warn("Cannot attach exemplars to the response time histogram", "check_id", check.ID, "err", err)
+		}
+		if m != nil {
+			ch <- m
+		}
+	}
+
+	if events != nil {
+		events.observe(results)
+		labelValues := pc.checkLabelValues(check, id, check.Name, check.Hostname)
+		ch <- prometheus.MustNewConstMetricWithCreatedTimestamp(pc.desc(pingdomSLIGoodEventsMetric), prometheus.CounterValue, events.good, events.created, labelValues...)
+		ch <- prometheus.MustNewConstMetricWithCreatedTimestamp(pc.desc(pingdomSLITotalEventsMetric), prometheus.CounterValue, events.total, events.created, labelValues...)
+	}
+
+	if window != nil {
+		window.observe(results, now.Add(-pc.outageCheckPeriod))
+		for _, slo := range latencySLOs {
+			good, total := window.events(slo.Threshold)
+			objective := formatFloat(percentToRatio(slo.Objective))
+			threshold := formatFloat(slo.Threshold.Seconds())
+
+			ch <- pc.checkMetric(pingdomLatencySLOGoodEventsMetric, check, good, id, check.Name, check.Hostname, objective, threshold)
+			ch <- pc.checkMetric(pingdomLatencySLOTotalEventsMetric, check, total, id, check.Name, check.Hostname, objective, threshold)
+			ch <- pc.checkMetric(pingdomLatencySLOErrorBudgetRemainingMetric, check, latencyErrorBudgetRemaining(slo, good, total), id, check.Name, check.Hostname, objective, threshold)
+		}
+	}
+}
+
+// staleOutageSummary returns the last known good outage data for the given
+// check, if serving stale data is enabled and the data is recent enough.
+func (pc *pingdomCollector) staleOutageSummary(checkID int) (outageSummary, bool) {
+	if pc.staleOutageMaxAge <= 0 {
+		return outageSummary{}, false
+	}
+
+	pc.mu.Lock()
+	summary, ok := pc.lastOutages[checkID]
+	pc.mu.Unlock()
+
+	if !ok || pc.now().Sub(summary.fetchedAt) > pc.staleOutageMaxAge {
+		return outageSummary{}, false
+	}
+
+	return summary, true
+}
+
+// countAPIError increments the Pingdom API error counter for the given
+// endpoint, using the HTTP status code of the error when available.
+func (pc *pingdomCollector) countAPIError(endpoint string, err error) {
+	code := "none"
+
+	var apiErr *pingdom.Error
+	if errors.As(err, &apiErr) {
+		code = strconv.Itoa(apiErr.StatusCode)
+	}
+
+	pc.apiErrors.WithLabelValues(endpoint, code).Inc()
+}
@@ -0,0 +1,708 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// testNow is the point in time the collector runs at in the tests.
+var testNow = time.Unix(1700000000, 0)
+
+func testCheck(id int, name, status string, tags ...string) pingdom.CheckResponse {
+	check := pingdom.CheckResponse{
+		ID:               id,
+		Name:             name,
+		Hostname:         name + ".example.com",
+		Resolution:       1,
+		Status:           status,
+		LastTestTime:     testNow.Add(-time.Minute).Unix(),
+		LastResponseTime: 250,
+		Type:             pingdom.CheckResponseType{Name: "http"},
+	}
+	for _, tag := range tags {
+		check.Tags = append(check.Tags, pingdom.CheckResponseTag{Name: tag, Type: "u", Count: float64(1)})
+	}
+	return check
+}
+
+// testOutages returns the outage states of a check which was down for the
+// given duration, two days ago.
+func testOutages(down time.Duration) []pingdom.OutageSummaryResponseState {
+	ts := func(d time.Duration) int64 {
+		return testNow.Add(-d).Unix()
+	}
+	day := 24 * time.Hour
+
+	return []pingdom.OutageSummaryResponseState{
+		{Status: "up", FromTime: ts(30 * day), ToTime: ts(2 * day)},
+		{Status: "down", FromTime: ts(2 * day), ToTime: ts(2*day - down)},
+		{Status: "up", FromTime: ts(2*day - down), ToTime: ts(0)},
+	}
+}
+
+func TestPingdomCollectorCollect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scenario pingdomtest.Scenario
+		include  []string
+		exclude  []string
+		labels   []tagLabelRule
+		rawTags  bool
+		stable   bool
+		services []serviceConfig
+	}{
+		{
+			name: "success",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up"),
+					testCheck(2, "web", "down", "frontend"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(2 * time.Hour),
+				},
+				RateLimit: &pingdomtest.RateLimit{
+					Short:      100,
+					ShortReset: pingdomtest.Duration(time.Hour),
+					Long:       1000,
+					LongReset:  pingdomtest.Duration(24 * time.Hour),
+				},
+			},
+		},
+		{
+			name: "checks_failure",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up"),
+				},
+				Faults: []pingdomtest.Fault{
+					{Path: "/checks", Status: http.StatusInternalServerError},
+				},
+			},
+		},
+		{
+			name: "auth_failure",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up"),
+				},
+				Faults: []pingdomtest.Fault{
+					{Path: "/checks", Status: http.StatusUnauthorized, Message: "Invalid token"},
+				},
+			},
+		},
+		{
+			name: "partial_failure",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up"),
+					testCheck(2, "web", "up"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(10 * time.Minute),
+				},
+				Faults: []pingdomtest.Fault{
+					{Path: "/summary.outage/2", Status: http.StatusInternalServerError},
+				},
+			},
+		},
+		{
+			name: "paused",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up"),
+					testCheck(2, "legacy", "paused"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+				},
+			},
+		},
+		{
+			name: "ignored",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up"),
+					testCheck(2, "staging", "down", "pingdom_exporter_ignored"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(10 * time.Minute),
+				},
+			},
+		},
+		{
+			name: "filtered",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up", "team:payments"),
+					testCheck(2, "web", "up", "team:frontend"),
+					testCheck(3, "api-staging", "up", "team:payments"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(10 * time.Minute),
+					3: testOutages(10 * time.Minute),
+				},
+			},
+			include: []string{"team=payments"},
+			exclude: []string{"name=*-staging"},
+		},
+		{
+			name: "tag_labels",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up", "team:payments", "env_prod"),
+					testCheck(2, "web", "up", "team:frontend"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(10 * time.Minute),
+				},
+			},
+			labels: []tagLabelRule{
+				{Label: "team", Prefix: "team:"},
+				{Label: "env", Regex: "env_(.+)"},
+			},
+		},
+		{
+			name: "stable_labels",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up"),
+					testCheck(2, "web", "unconfirmed_down", "frontend"),
+					testCheck(3, "legacy", "paused"),
+					testCheck(4, "batch", "unexpected"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(10 * time.Minute),
+				},
+			},
+			stable: true,
+		},
+		{
+			name: "custom_slo",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up", "uptime_slo_999"),
+					testCheck(2, "web", "up", "frontend", "uptime_slo_995"),
+					testCheck(3, "batch", "up", "uptime_slo_95"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(10 * time.Minute),
+					3: testOutages(10 * time.Minute),
+				},
+			},
+		},
+		{
+			name: "invalid_slo",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up", "uptime_slo_9"),
+					testCheck(2, "web", "up", "uptime_slo_999", "uptime_slo_995", "uptime_slo_99.9"),
+					testCheck(3, "batch", "up", "uptime_slo_99.5", "uptime_slo_95"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(10 * time.Minute),
+					3: testOutages(10 * time.Minute),
+				},
+			},
+		},
+		{
+			name: "services",
+			scenario: pingdomtest.Scenario{
+				Checks: []pingdom.CheckResponse{
+					testCheck(1, "api", "up", "team:shop"),
+					testCheck(2, "web", "up", "team:shop"),
+					testCheck(3, "cdn", "up"),
+					testCheck(4, "legacy", "up", "team:shop", "pingdom_exporter_ignored"),
+				},
+				Outages: map[int][]pingdom.OutageSummaryResponseState{
+					1: testOutages(10 * time.Minute),
+					2: testOutages(30 * time.Minute),
+					3: testOutages(0),
+					4: testOutages(time.Hour),
+				},
+			},
+			services: []serviceConfig{
+				{Name: "shop", Selector: "team=shop"},
+				{Name: "shop-any", Selector: "team=shop", Mode: serviceModeAnyUp},
+				{Name: "shop-weighted", Selector: "team=shop", Checks: []int{3}, Mode: serviceModeWeighted, Weights: map[int]float64{2: 3}, UptimeSLO: 99.9},
+				{Name: "blog", Selector: "team=blog"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := pingdomtest.NewServer(testCase.scenario)
+			defer server.Close()
+
+			client, err := server.NewClient(pingdom.ClientConfig{})
+			require.NoError(t, err)
+
+			collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+			collector.now = func() time.Time { return testNow }
+			collector.stableLabels = testCase.stable
+			collector.filter, err = newCheckFilter(testCase.include, testCase.exclude)
+			require.NoError(t, err)
+			if testCase.labels != nil {
+				collector.tagLabels, err = newTagLabeler(testCase.labels, testCase.rawTags, checkMetricLabels)
+				require.NoError(t, err)
+			}
+			collector.services, err = newServices(testCase.services, 99)
+			require.NoError(t, err)
+
+			assertGolden(t, collector, filepath.Join("testdata", testCase.name+".prom"))
+		})
+	}
+}
+
+func TestPingdomCollectorServeStaleOutages(t *testing.T) {
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			testCheck(1, "api", "up"),
+			testCheck(2, "web", "up"),
+			testCheck(3, "batch", "up"),
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{
+			1: testOutages(10 * time.Minute),
+			2: testOutages(10 * time.Minute),
+			3: testOutages(10 * time.Minute),
+		},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	now := testNow.Add(-2 * time.Hour)
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.staleOutageMaxAge = 90 * time.Minute
+	collector.now = func() time.Time { return now }
+
+	// Check 3 fails from the second collection on, so its last known good
+	// data is too old to be served by the third one
+	testutil.CollectAndCount(collector)
+	now = testNow.Add(-time.Hour)
+	server.SetScenario(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			testCheck(1, "api", "up"),
+			testCheck(2, "web", "up"),
+			testCheck(3, "batch", "up"),
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{
+			1: testOutages(10 * time.Minute),
+			2: testOutages(10 * time.Minute),
+		},
+		Faults: []pingdomtest.Fault{
+			{Path: "/summary.outage/3", Status: http.StatusServiceUnavailable},
+		},
+	})
+	testutil.CollectAndCount(collector)
+
+	now = testNow
+	server.SetScenario(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			testCheck(1, "api", "up"),
+			testCheck(2, "web", "up"),
+			testCheck(3, "batch", "up"),
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{
+			2: testOutages(20 * time.Minute),
+		},
+		Faults: []pingdomtest.Fault{
+			{Path: "/summary.outage/1", Status: http.StatusServiceUnavailable},
+			{Path: "/summary.outage/3", Status: http.StatusServiceUnavailable},
+		},
+	})
+
+	assertGolden(t, collector, filepath.Join("testdata", "stale_outages.prom"))
+}
+
+func TestPingdomCollectorOutageCounter(t *testing.T) {
+	day := 24 * time.Hour
+	outages := testOutages(10 * time.Minute)
+
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks:  []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: outages},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	now := testNow.Add(-day)
+	collector := newPingdomCollector(client, 7*day, 99)
+	collector.now = func() time.Time { return now }
+
+	counterValue := func() *dto.Counter {
+		registry := prometheus.NewPedanticRegistry()
+		require.NoError(t, registry.Register(collector))
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "pingdom_outages_total" {
+				return family.Metric[0].GetCounter()
+			}
+		}
+		require.Fail(t, "pingdom_outages_total not found")
+		return nil
+	}
+
+	// The outages within the outage check period are counted at first
+	counter := counterValue()
+	assert.Equal(t, 1.0, counter.GetValue())
+	assert.Equal(t, now.Add(-7*day).Unix(), counter.GetCreatedTimestamp().GetSeconds())
+
+	// A new outage is counted once, even while ongoing, and the outages
+	// leaving the outage check period are not discounted
+	now = testNow
+	server.SetScenario(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: {
+			{Status: "up", FromTime: testNow.Add(-7 * day).Unix(), ToTime: testNow.Add(-time.Hour).Unix()},
+			{Status: "down", FromTime: testNow.Add(-time.Hour).Unix(), ToTime: testNow.Unix()},
+		}},
+	})
+	assert.Equal(t, 2.0, counterValue().GetValue())
+	assert.Equal(t, 2.0, counterValue().GetValue())
+	assert.Equal(t, testNow.Add(-8*day).Unix(), counterValue().GetCreatedTimestamp().GetSeconds())
+}
+
+func TestPingdomCollectorOutageCounterClippedState(t *testing.T) {
+	day := 24 * time.Hour
+
+	// The down state started before the outage check period, so the Pingdom
+	// API clips its start to the beginning of the period on every scrape
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: {
+			{Status: "down", FromTime: testNow.Add(-8 * day).Unix(), ToTime: testNow.Add(-6 * day).Unix()},
+			{Status: "up", FromTime: testNow.Add(-6 * day).Unix(), ToTime: testNow.Add(time.Hour).Unix()},
+		}},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	now := testNow
+	collector := newPingdomCollector(client, 7*day, 99)
+	collector.now = func() time.Time { return now }
+
+	for range 4 {
+		registry := prometheus.NewPedanticRegistry()
+		require.NoError(t, registry.Register(collector))
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP pingdom_outages_total Number of outages of the check since the beginning of the outage check period when the exporter started
+# TYPE pingdom_outages_total counter
+pingdom_outages_total{hostname="api.example.com",id="1",name="api",tags=""} 1
+`), "pingdom_outages_total"))
+		now = now.Add(time.Minute)
+	}
+}
+
+func TestPingdomCollectorResults(t *testing.T) {
+	result := func(probeID int, ago time.Duration, status string, responseTime int64) pingdom.ResultsResponseResult {
+		return pingdom.ResultsResponseResult{ProbeID: probeID, Time: testNow.Add(-ago).Unix(), Status: status, ResponseTime: responseTime}
+	}
+
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks:  []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: testOutages(10 * time.Minute)},
+		Results: map[int][]pingdom.ResultsResponseResult{1: {
+			result(63, time.Minute, "up", 120),
+			result(64, 2*time.Minute, "down", 0),
+			result(65, 3*time.Minute, "up", 700),
+			result(63, 4*time.Minute, "up", 80),
+			result(64, 2*time.Hour, "up", 100),
+		}},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.now = func() time.Time { return testNow }
+	collector.collectResults = true
+	collector.resultURLTemplate = "https://pingdom.example.com/{check_id}/{probe_id}/{time}"
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0")
+	rec := httptest.NewRecorder()
+	NewServer(registry, "/metrics", collector.Ready, slog.Default()).ServeHTTP(rec, req)
+
+	// Results older than the initial window and failed ones are not observed
+	body := rec.Body.String()
+	labels := `hostname="api.example.com",id="1",name="api",tags=""`
+	assert.Contains(t, body, "# TYPE pingdom_check_response_time_seconds histogram\n")
+	assert.Contains(t, body, `pingdom_check_response_time_seconds_bucket{`+labels+`,le="0.1"} 1 # {probe_id="63",url="https://pingdom.example.com/1/63/1699999760"} 0.08 1.69999976e+09`)
+	assert.Contains(t, body, `pingdom_check_response_time_seconds_bucket{`+labels+`,le="0.25"} 2 # {probe_id="63",url="https://pingdom.example.com/1/63/1699999940"} 0.12 1.69999994e+09`)
+	assert.Contains(t, body, `pingdom_check_response_time_seconds_bucket{`+labels+`,le="1.0"} 3 # {probe_id="65",url="https://pingdom.example.com/1/65/1699999820"} 0.7 1.69999982e+09`)
+	assert.Contains(t, body, `pingdom_check_response_time_seconds_bucket{`+labels+`,le="0.05"} 0`+"\n")
+	assert.Contains(t, body, `pingdom_check_response_time_seconds_bucket{`+labels+`,le="30.0"} 3`+"\n")
+	assert.Contains(t, body, `pingdom_check_response_time_seconds_count{`+labels+`} 3`)
+	assert.Contains(t, body, `pingdom_check_response_time_seconds_created{`+labels+`} 1.6999964e+09`)
+	assert.Contains(t, body, `pingdom_outages_created{`+labels+`} 1.6993952e+09`)
+
+	assert.Equal(t, []string{
+		"/checks?include_severity=true&include_tags=true&tags=",
+		"/results/1?from=1699996400&limit=1000&to=1700000000",
+		"/summary.outage/1?from=1699395200&to=1700000000",
+	}, server.Requests())
+}
+
+func TestPingdomCollectorLatencySLO(t *testing.T) {
+	result := func(ago time.Duration, status string, responseTime int64) pingdom.ResultsResponseResult {
+		return pingdom.ResultsResponseResult{ProbeID: 63, Time: testNow.Add(-ago).Unix(), Status: status, ResponseTime: responseTime}
+	}
+
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks:  []pingdom.CheckResponse{testCheck(1, "api", "up", "latency_slo_p75_500ms", "latency_slo_99_1s", "latency_slo_p95")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: testOutages(10 * time.Minute)},
+		Results: map[int][]pingdom.ResultsResponseResult{1: {
+			result(time.Minute, "up", 120),
+			result(2*time.Minute, "down", 0),
+			result(3*time.Minute, "up", 700),
+			result(4*time.Minute, "up", 80),
+			result(2*24*time.Hour, "up", 100),
+			result(8*24*time.Hour, "up", 900),
+		}},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.now = func() time.Time { return testNow }
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	// Results older than the outage check period and failed ones are not
+	// evaluated
+	labels := `hostname="api.example.com",id="1",name="api"`
+	tags := `tags="latency_slo_p75_500ms,latency_slo_99_1s,latency_slo_p95"`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP pingdom_latency_slo_error_budget_remaining_ratio Ratio of the tests allowed to respond slower than the threshold of the latency SLO within the outage check period which are left; negative once the latency SLO is broken
+# TYPE pingdom_latency_slo_error_budget_remaining_ratio gauge
+pingdom_latency_slo_error_budget_remaining_ratio{`+labels+`,objective="0.75",`+tags+`,threshold="0.5"} 0
+pingdom_latency_slo_error_budget_remaining_ratio{`+labels+`,objective="0.99",`+tags+`,threshold="1"} 1
+# HELP pingdom_latency_slo_good_events Number of successful tests of the check within the outage check period which responded within the threshold of the latency SLO
+# TYPE pingdom_latency_slo_good_events gauge
+pingdom_latency_slo_good_events{`+labels+`,objective="0.75",`+tags+`,threshold="0.5"} 3
+pingdom_latency_slo_good_events{`+labels+`,objective="0.99",`+tags+`,threshold="1"} 4
+# HELP pingdom_latency_slo_total_events Number of successful tests of the check within the outage check period, against which the latency SLO is evaluated
+# TYPE pingdom_latency_slo_total_events gauge
+pingdom_latency_slo_total_events{`+labels+`,objective="0.75",`+tags+`,threshold="0.5"} 4
+pingdom_latency_slo_total_events{`+labels+`,objective="0.99",`+tags+`,threshold="1"} 4
+# HELP pingdom_check_slo_config_error Whether the check has SLO tags which are invalid for the given reason (unparseable, out_of_range, conflicting) and ignored; only exported when they are
+# TYPE pingdom_check_slo_config_error gauge
+pingdom_check_slo_config_error{`+labels+`,reason="unparseable",`+tags+`} 1
+`), "pingdom_latency_slo_good_events", "pingdom_latency_slo_total_events", "pingdom_latency_slo_error_budget_remaining_ratio", "pingdom_check_slo_config_error"))
+
+	// Following scrapes only retrieve the results since the second of the
+	// last one observed
+	_, err = registry.Gather()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/checks?include_severity=true&include_tags=true&tags=",
+		"/results/1?from=1699395200&limit=1000&to=1700000000",
+		"/summary.outage/1?from=1699395200&to=1700000000",
+		"/checks?include_severity=true&include_tags=true&tags=",
+		"/results/1?from=1699999940&limit=1000&to=1700000000",
+		"/summary.outage/1?from=1699395200&to=1700000000",
+	}, server.Requests())
+}
+
+func TestPingdomCollectorSLIEvents(t *testing.T) {
+	result := func(probeID int, ago time.Duration, status string) pingdom.ResultsResponseResult {
+		return pingdom.ResultsResponseResult{ProbeID: probeID, Time: testNow.Add(-ago).Unix(), Status: status, ResponseTime: 100}
+	}
+
+	scenario := pingdomtest.Scenario{
+		Checks:  []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: testOutages(10 * time.Minute)},
+		Results: map[int][]pingdom.ResultsResponseResult{1: {
+			result(63, time.Minute, "up"),
+			result(63, 2*time.Minute, "down"),
+			result(63, 3*time.Minute, "unconfirmed_down"),
+			result(63, 4*time.Minute, "up"),
+			result(63, 2*24*time.Hour, "up"),
+			result(63, 3*24*time.Hour, "unknown"),
+			result(63, 7*24*time.Hour, "up"),
+			result(63, 8*24*time.Hour, "down"),
+		}},
+	}
+	server := pingdomtest.NewServer(scenario)
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.now = func() time.Time { return testNow }
+	collector.collectEvents = true
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	counters := func() map[string]*dto.Counter {
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		counters := map[string]*dto.Counter{}
+		for _, family := range families {
+			if strings.HasPrefix(family.GetName(), "pingdom_sli_") {
+				counters[family.GetName()] = family.Metric[0].GetCounter()
+			}
+		}
+		return counters
+	}
+
+	// Results older than the outage check period and the ones neither up nor
+	// down are not counted, and following scrapes count the new results only
+	for range 2 {
+		c := counters()
+		require.Len(t, c, 2)
+		assert.Equal(t, 4.0, c["pingdom_sli_good_events_total"].GetValue())
+		assert.Equal(t, 5.0, c["pingdom_sli_total_events_total"].GetValue())
+		assert.Equal(t, testNow.Add(-7*24*time.Hour).Unix(), c["pingdom_sli_total_events_total"].GetCreatedTimestamp().GetSeconds())
+	}
+
+	assert.Equal(t, []string{
+		"/checks?include_severity=true&include_tags=true&tags=",
+		"/results/1?from=1699395200&limit=1000&to=1700000000",
+		"/summary.outage/1?from=1699395200&to=1700000000",
+		"/checks?include_severity=true&include_tags=true&tags=",
+		"/results/1?from=1699999940&limit=1000&to=1700000000",
+		"/summary.outage/1?from=1699395200&to=1700000000",
+	}, server.Requests())
+
+	// A result of another probe within the second of the last one counted
+	// is counted once retrieved
+	scenario.Results[1] = append(scenario.Results[1], result(64, time.Minute, "down"))
+	server.SetScenario(scenario)
+	for range 2 {
+		c := counters()
+		assert.Equal(t, 4.0, c["pingdom_sli_good_events_total"].GetValue())
+		assert.Equal(t, 6.0, c["pingdom_sli_total_events_total"].GetValue())
+	}
+}
+
+func TestPingdomCollectorDefaultMinUptimeSLO(t *testing.T) {
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks:  []pingdom.CheckResponse{testCheck(1, "api", "up", "uptime_slo_9")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: testOutages(0)},
+	})
+	defer server.Close()
+
+	t.Setenv("PINGDOM_API_TOKEN", "secret")
+	defer func(url string) { baseURL = url }(baseURL)
+	baseURL = server.URL
+
+	// Without flags, uptime SLO tags below 90% are out of range
+	collector, _, err := newCollectorFromFlags(slog.Default(), nil)
+	require.NoError(t, err)
+	collector.now = func() time.Time { return testNow }
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	labels := `hostname="api.example.com",id="1",name="api"`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP pingdom_check_slo_config_error Whether the check has SLO tags which are invalid for the given reason (unparseable, out_of_range, conflicting) and ignored; only exported when they are
+# TYPE pingdom_check_slo_config_error gauge
+pingdom_check_slo_config_error{`+labels+`,reason="out_of_range",tags="uptime_slo_9"} 1
+# HELP pingdom_check_uptime_slo_ratio Uptime SLO of the check, as a ratio, configured either via a tag or the default uptime SLO
+# TYPE pingdom_check_uptime_slo_ratio gauge
+pingdom_check_uptime_slo_ratio{`+labels+`,source="default",tags="uptime_slo_9"} 0.99
+`), "pingdom_check_slo_config_error", "pingdom_check_uptime_slo_ratio"))
+}
+
+func TestPingdomCollectorReady(t *testing.T) {
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Faults: []pingdomtest.Fault{
+			{Path: "/checks", Status: http.StatusInternalServerError, Count: 1},
+		},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	now := testNow
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.now = func() time.Time { return now }
+	assert.False(t, collector.Ready())
+
+	// The Pingdom API is requested again only after readyCheckInterval
+	now = now.Add(readyCheckInterval - time.Second)
+	assert.False(t, collector.Ready())
+	now = now.Add(time.Second)
+	assert.True(t, collector.Ready())
+	assert.True(t, collector.Ready())
+
+	assert.Equal(t, []string{"/checks?limit=1", "/checks?limit=1"}, server.Requests())
+}
+
+// assertGolden compares the metrics exposed by the collector with the ones in
+// the given golden file, which is rewritten instead when -update is set.
+// Collecting is not idempotent against the fake Pingdom API, since it tracks
+// rate limits, so the collector is only collected once either way.
+func assertGolden(t *testing.T, collector prometheus.Collector, path string) {
+	t.Helper()
+
+	if *update {
+		registry := prometheus.NewPedanticRegistry()
+		require.NoError(t, registry.Register(collector))
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		for _, family := range families {
+			_, err := expfmt.MetricFamilyToText(&buf, family)
+			require.NoError(t, err)
+		}
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+		return
+	}
+
+	expected, err := os.Open(path)
+	require.NoError(t, err)
+	defer expected.Close()
+
+	require.NoError(t, testutil.CollectAndCompare(collector, expected))
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// command is a subcommand of the exporter, i.e. `pingdom-exporter backfill`.
+// Commands accept the flags selecting, labeling and computing the SLO of the
+// checks, so they see the checks as the exporter does, along with their own.
+type command struct {
+	name  string
+	args  string
+	help  string
+	flags func(fs *flag.FlagSet)
+	run   func(ctx context.Context, c *commandContext) error
+}
+
+// commandContext is what a command runs with.
+type commandContext struct {
+	// collector is configured as the exporter's, i.e. with the check
+	// filter and tag labels.
+	collector *pingdomCollector
+	config    *config
+	args      []string
+	stdout    io.Writer
+	logger    *slog.Logger
+}
+
+// commands are the subcommands of the exporter, by name.
+var commands = map[string]*command{
+	"backfill": backfillCommand,
+	"checks":   checksCommand,
+	"explain":  explainCommand,
+	"outages":  outagesCommand,
+	"report":   reportCommand,
+}
+
+// commandFlagNames are the flags of the exporter also accepted by commands.
+var commandFlagNames = []string{
+	"checks.exclude",
+	"checks.include",
+	"config.file",
+	"default-uptime-slo",
+	"labels.exclude",
+	"labels.include",
+	"log-format",
+	"log-level",
+	"metrics.exclude",
+	"metrics.include",
+	"metrics.stable-labels",
+	"min-uptime-slo",
+	"outage-check-period",
+	"pingdom-base-url",
+	"pingdom-record-dir",
+	"pingdom-replay-dir",
+	"pingdom.token-file",
+	"tags",
+	"tags.label-prefix",
+	"tags.label-regex",
+	"tags.raw-label",
+}
+
+// usage prints the usage of the exporter, listing the commands.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(out, "  %s [flags]\n", os.Args[0])
+	fmt.Fprintf(out, "  %s <command> [flags]\n\nCommands:\n", os.Args[0])
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-10s %s\n", name, commands[name].help)
+	}
+
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// runCommand parses the flags of the command and runs it, returning the exit
+// status of the process.
+func runCommand(cmd *command, args []string) int {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s\n\n%s\n\nFlags:\n", strings.TrimSpace(os.Args[0]+" "+cmd.name+" [flags] "+cmd.args), cmd.help)
+		fs.PrintDefaults()
+	}
+
+	for _, name := range commandFlagNames {
+		f := flag.Lookup(name)
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+
+	positional := parseInterspersed(fs, args)
+	activeFlags = fs
+
+	logger, err := newLogger(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v, exiting\n", err)
+		return 1
+	}
+	slog.SetDefault(logger)
+
+	collector, cfg, err := newCollectorFromFlags(logger, nil)
+	if err != nil {
+		logger.Error("Cannot set up the exporter, exiting", "err", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = cmd.run(ctx, &commandContext{
+		collector: collector,
+		config:    cfg,
+		args:      positional,
+		stdout:    os.Stdout,
+		logger:    logger,
+	})
+	if err != nil {
+		logger.Error("Command failed", "command", cmd.name, "err", err)
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses the flags, which may be given before or after the
+// positional arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		// Errors exit the process, as with flag.ExitOnError
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parsePeriod parses the period of time given to a command via its -from and
+// -to flags. The end defaults to now, and the start to the given duration
+// before the end.
+func parsePeriod(fromFlag, toFlag string, now time.Time, defaultPeriod time.Duration) (from, to time.Time, err error) {
+	to = now
+	if toFlag != "" {
+		if to, err = parseTime(toFlag, now); err != nil {
+			return from, to, err
+		}
+	}
+
+	from = to.Add(-defaultPeriod)
+	if fromFlag != "" {
+		if from, err = parseTime(fromFlag, now); err != nil {
+			return from, to, err
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("-from %s must be before -to %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	return from, to, nil
+}
+
+// writeOutput writes the output of a command to the given file, or to the
+// standard output when the path is -.
+func writeOutput(path string, stdout io.Writer, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// validateOutputFormat returns an error unless the output format of a command
+// listing things is table or json.
+func validateOutputFormat(format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid output format %q", format)
+	}
+	return nil
+}
+
+// writeJSON writes the output of a command as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// parseTime parses a point in time given to a command, either as a RFC 3339
+// timestamp, a date, or a duration relative to now, i.e. 7d or 12h.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.UTC); err == nil {
+		return t, nil
+	}
+	if d, err := parseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a RFC 3339 timestamp, a date or a duration", s)
+}
+
+// parseDuration parses a duration, which may be given in days, i.e. 7d.
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
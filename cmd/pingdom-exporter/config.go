@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// config is the configuration file of the exporter, given via -config.file.
+type config struct {
+	Checks  checksConfig  `yaml:"checks"`
+	Tags    tagsConfig    `yaml:"tags"`
+	Metrics metricsConfig `yaml:"metrics"`
+	Labels  labelsConfig  `yaml:"labels"`
+
+	Services []serviceConfig `yaml:"services"`
+}
+
+// checksConfig selects the checks to be exported, see checkFilter.
+type checksConfig struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// tagsConfig configures the labels derived from the tags of the checks, see
+// tagLabeler.
+type tagsConfig struct {
+	Labels []tagLabelRule `yaml:"labels"`
+
+	// RawLabel sets whether to keep the "tags" label with all the tags of the
+	// check; defaults to true.
+	RawLabel *bool `yaml:"raw_label"`
+}
+
+// metricsConfig selects the metric families to be exposed and caps the
+// number of series, see seriesSelector.
+type metricsConfig struct {
+	Include   []string `yaml:"include"`
+	Exclude   []string `yaml:"exclude"`
+	MaxSeries int      `yaml:"max_series"`
+
+	// StableLabels enables the metric layout with stable labels, see
+	// pingdomCollector.stableLabels.
+	StableLabels bool `yaml:"stable_labels"`
+}
+
+// labelsConfig selects the labels to be exposed, see seriesSelector.
+type labelsConfig struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// serviceConfig defines a composite service, see service.
+type serviceConfig struct {
+	Name string `yaml:"name"`
+
+	// The checks of the service, matching the selector, i.e. "tag=shop", or
+	// in the list of check ids.
+	Selector string `yaml:"selector"`
+	Checks   []int  `yaml:"checks"`
+
+	// Mode is one of all_up (default), any_up or weighted, with the weights
+	// of the checks by check id, defaulting to 1.
+	Mode    string          `yaml:"mode"`
+	Weights map[int]float64 `yaml:"weights"`
+
+	// UptimeSLO defaults to -default-uptime-slo.
+	UptimeSLO float64 `yaml:"uptime_slo"`
+}
+
+// loadConfig reads the configuration file at the given path, rejecting
+// unknown fields.
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{}
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+checks:
+  include:
+    - team=payments
+  exclude:
+    - 'name=~"(?i).*staging.*"'
+tags:
+  labels:
+    - label: team
+      prefix: "team:"
+    - label: env
+      regex: "env_(.+)"
+  raw_label: false
+metrics:
+  exclude:
+    - go_*
+  max_series: 1000
+  stable_labels: true
+labels:
+  exclude:
+    - hostname
+services:
+  - name: shop
+    selector: team=shop
+    checks: [123]
+    mode: weighted
+    weights:
+      123: 2
+    uptime_slo: 99.9
+`), 0600))
+
+	cfg, err := loadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team=payments"}, cfg.Checks.Include)
+	assert.Equal(t, []string{`name=~"(?i).*staging.*"`}, cfg.Checks.Exclude)
+	assert.Equal(t, []tagLabelRule{{Label: "team", Prefix: "team:"}, {Label: "env", Regex: "env_(.+)"}}, cfg.Tags.Labels)
+	require.NotNil(t, cfg.Tags.RawLabel)
+	assert.False(t, *cfg.Tags.RawLabel)
+	assert.Equal(t, metricsConfig{Exclude: []string{"go_*"}, MaxSeries: 1000, StableLabels: true}, cfg.Metrics)
+	assert.Equal(t, labelsConfig{Exclude: []string{"hostname"}}, cfg.Labels)
+	assert.Equal(t, []serviceConfig{{Name: "shop", Selector: "team=shop", Checks: []int{123}, Mode: "weighted", Weights: map[int]float64{123: 2}, UptimeSLO: 99.9}}, cfg.Services)
+
+	require.NoError(t, os.WriteFile(path, []byte("checks:\n  includes: []\n"), 0600))
+	_, err = loadConfig(path)
+	assert.ErrorContains(t, err, "field includes not found")
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+// errCheckNotFound is returned when explaining a check which doesn't exist.
+var errCheckNotFound = errors.New("check not found")
+
+var explainFormat string
+
+var explainCommand = &command{
+	name: "explain",
+	args: "<check id>",
+	help: "explain how the error budget of a check is computed, from its outage states within the outage check period and its uptime SLO",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&explainFormat, "format", "table", "output format (one of: table, json)")
+	},
+	run: runExplain,
+}
+
+// explanation details how the collector computes the up and down time and
+// the error budget of a check, as they would be exported at a given time.
+type explanation struct {
+	CheckID  int    `json:"check_id"`
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+
+	// Exported is false when the check is ignored via its tags or doesn't
+	// match the check filter, in which case its metrics aren't exported.
+	Exported bool `json:"exported"`
+	Ignored  bool `json:"ignored"`
+
+	// The window is the outage check period ending now.
+	WindowFrom time.Time `json:"window_from"`
+	WindowTo   time.Time `json:"window_to"`
+
+	UptimeSLO       float64 `json:"uptime_slo"`
+	UptimeSLOSource string  `json:"uptime_slo_source"`
+	UptimeSLOTag    string  `json:"uptime_slo_tag,omitempty"`
+
+	// SLOTagErrors are the invalid uptime SLO tags of the check, which
+	// are ignored.
+	SLOTagErrors []string `json:"uptime_slo_errors,omitempty"`
+
+	Intervals []explainedInterval `json:"intervals"`
+
+	UpTimeSeconds               float64 `json:"up_seconds"`
+	DownTimeSeconds             float64 `json:"down_seconds"`
+	ErrorBudgetSeconds          float64 `json:"error_budget_seconds"`
+	ErrorBudgetAvailableSeconds float64 `json:"error_budget_available_seconds"`
+}
+
+// explainedInterval is an outage state of the check, as returned by
+// Pingdom, with the time it adds to the up or down time of the check and
+// the error budget available once it is accounted for.
+type explainedInterval struct {
+	Status                      string    `json:"status"`
+	From                        time.Time `json:"from"`
+	To                          time.Time `json:"to"`
+	UpTimeSeconds               float64   `json:"up_seconds"`
+	DownTimeSeconds             float64   `json:"down_seconds"`
+	ErrorBudgetAvailableSeconds float64   `json:"error_budget_available_seconds"`
+}
+
+// explain retrieves the check and its outage states within the outage check
+// period, and computes its error budget as Collect does.
+func (pc *pingdomCollector) explain(checkID int) (*explanation, error) {
+	check, exported, err := pc.findCheck(checkID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := pc.now()
+	uptimeSLO := pc.uptimeSLO(check)
+	e := &explanation{
+		CheckID:         check.ID,
+		Name:            check.Name,
+		Hostname:        check.Hostname,
+		Exported:        exported,
+		Ignored:         check.HasIgnoreTag(),
+		WindowFrom:      now.Add(-pc.outageCheckPeriod).UTC(),
+		WindowTo:        now.UTC(),
+		UptimeSLO:       uptimeSLO.Value,
+		UptimeSLOSource: uptimeSLO.Source,
+		UptimeSLOTag:    uptimeSLO.Tag,
+		Intervals:       []explainedInterval{},
+	}
+	for _, err := range uptimeSLO.Errors {
+		e.SLOTagErrors = append(e.SLOTagErrors, err.Error())
+	}
+
+	states, err := pc.client.OutageSummary.List(check.ID, map[string]string{
+		"from": strconv.FormatInt(e.WindowFrom.Unix(), 10),
+		"to":   strconv.FormatInt(e.WindowTo.Unix(), 10),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve outages: %w", err)
+	}
+
+	e.ErrorBudgetSeconds = pc.outageCheckPeriod.Seconds() * (100.0 - e.UptimeSLO) / 100.0
+	e.ErrorBudgetAvailableSeconds = e.ErrorBudgetSeconds
+
+	// As in fetchOutageSummary, the whole duration of the up and down states
+	// is accounted for, while other states, i.e. unknown, are not
+	for _, state := range states {
+		interval := explainedInterval{
+			Status: state.Status,
+			From:   time.Unix(state.FromTime, 0).UTC(),
+			To:     time.Unix(state.ToTime, 0).UTC(),
+		}
+		switch state.Status {
+		case "down":
+			interval.DownTimeSeconds = float64(state.ToTime - state.FromTime)
+		case "up":
+			interval.UpTimeSeconds = float64(state.ToTime - state.FromTime)
+		}
+
+		e.UpTimeSeconds += interval.UpTimeSeconds
+		e.DownTimeSeconds += interval.DownTimeSeconds
+		e.ErrorBudgetAvailableSeconds -= interval.DownTimeSeconds
+		interval.ErrorBudgetAvailableSeconds = e.ErrorBudgetAvailableSeconds
+		e.Intervals = append(e.Intervals, interval)
+	}
+
+	return e, nil
+}
+
+// findCheck retrieves the check with the given id, returning whether the
+// collector exports it, i.e. it has one of the tags the checks are retrieved
+// with and isn't ignored or filtered out.
+func (pc *pingdomCollector) findCheck(checkID int) (pingdom.CheckResponse, bool, error) {
+	params := map[string]string{
+		"include_tags":     "true",
+		"include_severity": "true",
+		"tags":             pc.client.Tags,
+	}
+
+	for {
+		checks, _, err := pc.client.Checks.List(params)
+		if err != nil {
+			return pingdom.CheckResponse{}, false, fmt.Errorf("cannot retrieve checks: %w", err)
+		}
+
+		for _, check := range checks {
+			if check.ID == checkID {
+				return check, params["tags"] == pc.client.Tags && pc.exports(check), nil
+			}
+		}
+
+		// The check may exist without any of the tags
+		if params["tags"] == "" {
+			return pingdom.CheckResponse{}, false, errCheckNotFound
+		}
+		params["tags"] = ""
+	}
+}
+
+func runExplain(_ context.Context, c *commandContext) error {
+	if len(c.args) != 1 {
+		return errors.New("expected a check id")
+	}
+	checkID, err := strconv.Atoi(c.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid check id %q", c.args[0])
+	}
+	if err := validateOutputFormat(explainFormat); err != nil {
+		return err
+	}
+
+	e, err := c.collector.explain(checkID)
+	if err != nil {
+		return err
+	}
+
+	if explainFormat == "json" {
+		return writeJSON(c.stdout, e)
+	}
+	return writeExplanation(c.stdout, e)
+}
+
+// explainHandler serves the explanation of the check given by the check
+// query parameter, as JSON or, with format=text, as the explain command
+// prints it.
+func explainHandler(pc *pingdomCollector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkID, err := strconv.Atoi(r.URL.Query().Get("check"))
+		if err != nil {
+			http.Error(w, "The check parameter must be a check id", http.StatusBadRequest)
+			return
+		}
+
+		e, err := pc.explain(checkID)
+		if errors.Is(err, errCheckNotFound) {
+			http.Error(w, fmt.Sprintf("Check %d not found", checkID), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			pc.logger.Error("Error explaining check", "check_id", checkID, "err", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			writeExplanation(w, e)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, e)
+	})
+}
+
+// writeExplanation writes the explanation for humans, with durations rounded
+// to the millisecond and the exact values of the exported metrics.
+func writeExplanation(w io.Writer, e *explanation) error {
+	var b strings.Builder
+	d := func(seconds float64) time.Duration {
+		return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+	}
+
+	fmt.Fprintf(&b, "Check %d %s (%s)\n", e.CheckID, e.Name, e.Hostname)
+	switch {
+	case e.Ignored:
+		b.WriteString("Not exported: ignored via the pingdom_exporter_ignored tag\n")
+	case !e.Exported:
+		b.WriteString("Not exported: not selected by the check filter or tags\n")
+	}
+
+	fmt.Fprintf(&b, "Window: %s to %s (%s)\n", e.WindowFrom.Format(time.RFC3339), e.WindowTo.Format(time.RFC3339), e.WindowTo.Sub(e.WindowFrom))
+	if e.UptimeSLOSource == pingdom.UptimeSLOSourceTag {
+		fmt.Fprintf(&b, "Uptime SLO: %s%%, from the %s tag\n", formatFloat(e.UptimeSLO), e.UptimeSLOTag)
+	} else {
+		fmt.Fprintf(&b, "Uptime SLO: %s%%, the default\n", formatFloat(e.UptimeSLO))
+	}
+	for _, err := range e.SLOTagErrors {
+		fmt.Fprintf(&b, "Ignored %s\n", err)
+	}
+	fmt.Fprintf(&b, "Error budget: %s = %s x (100%% - %s%%)\n\n", d(e.ErrorBudgetSeconds), e.WindowTo.Sub(e.WindowFrom), formatFloat(e.UptimeSLO))
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tFROM\tTO\tUP\tDOWN\tBUDGET AVAILABLE")
+	for _, i := range e.Intervals {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", i.Status, i.From.Format(time.RFC3339), i.To.Format(time.RFC3339), d(i.UpTimeSeconds), d(i.DownTimeSeconds), d(i.ErrorBudgetAvailableSeconds))
+	}
+	tw.Flush()
+
+	b.WriteString("\n")
+	tw = tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\n", pingdomUpTimeMetric.name, formatFloat(e.UpTimeSeconds))
+	fmt.Fprintf(tw, "%s\t%s\n", pingdomDownTimeMetric.name, formatFloat(e.DownTimeSeconds))
+	fmt.Fprintf(tw, "%s\t%s\n", pingdomCheckErrorBudgetMetric.name, formatFloat(e.ErrorBudgetSeconds))
+	fmt.Fprintf(tw, "%s\t%s\n", pingdomCheckAvailableErrorBudgetMetric.name, formatFloat(e.ErrorBudgetAvailableSeconds))
+	tw.Flush()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExplainTestCollector(t *testing.T) *pingdomCollector {
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			testCheck(1, "api", "up", "uptime_slo_999"),
+			testCheck(2, "legacy", "up", "pingdom_exporter_ignored"),
+			testCheck(3, "web", "up", "uptime_slo_9"),
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{
+			1: testOutages(15 * time.Minute),
+			3: testOutages(0),
+		},
+	})
+	t.Cleanup(server.Close)
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.now = func() time.Time { return testNow }
+	collector.logger = slog.Default()
+	return collector
+}
+
+func TestExplain(t *testing.T) {
+	collector := newExplainTestCollector(t)
+
+	e, err := collector.explain(1)
+	require.NoError(t, err)
+
+	assert.True(t, e.Exported)
+	assert.True(t, testNow.Add(-7*24*time.Hour).Equal(e.WindowFrom))
+	assert.True(t, testNow.Equal(e.WindowTo))
+	assert.Equal(t, 99.9, e.UptimeSLO)
+	assert.Equal(t, pingdom.UptimeSLOSourceTag, e.UptimeSLOSource)
+	assert.Equal(t, "uptime_slo_999", e.UptimeSLOTag)
+
+	require.Len(t, e.Intervals, 3)
+	assert.Equal(t, "down", e.Intervals[1].Status)
+	assert.Equal(t, 900.0, e.Intervals[1].DownTimeSeconds)
+	assert.Equal(t, 0.0, e.Intervals[1].UpTimeSeconds)
+	assert.InDelta(t, 604.8-900, e.Intervals[1].ErrorBudgetAvailableSeconds, 1e-6)
+
+	assert.Equal(t, 900.0, e.DownTimeSeconds)
+	assert.Equal(t, 7*24*3600.0-900, e.UpTimeSeconds)
+	assert.InDelta(t, 604.8, e.ErrorBudgetSeconds, 1e-6)
+	assert.InDelta(t, 604.8-900, e.ErrorBudgetAvailableSeconds, 1e-6)
+
+	// Checks without a valid SLO tag use the default one
+	e, err = collector.explain(3)
+	require.NoError(t, err)
+	assert.Equal(t, 99.0, e.UptimeSLO)
+	assert.Equal(t, pingdom.UptimeSLOSourceDefault, e.UptimeSLOSource)
+	assert.Equal(t, []string{"invalid SLO tag uptime_slo_9: out_of_range"}, e.SLOTagErrors)
+
+	// Ignored and filtered out checks are explained, although not exported
+	e, err = collector.explain(2)
+	require.NoError(t, err)
+	assert.False(t, e.Exported)
+	assert.True(t, e.Ignored)
+
+	collector.filter, err = newCheckFilter(nil, []string{"name=web"})
+	require.NoError(t, err)
+	e, err = collector.explain(3)
+	require.NoError(t, err)
+	assert.False(t, e.Exported)
+	assert.False(t, e.Ignored)
+
+	_, err = collector.explain(4)
+	assert.ErrorIs(t, err, errCheckNotFound)
+}
+
+func TestWriteExplanation(t *testing.T) {
+	e, err := newExplainTestCollector(t).explain(1)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeExplanation(&buf, e))
+	assert.Equal(t, `Check 1 api (api.example.com)
+Window: 2023-11-07T22:13:20Z to 2023-11-14T22:13:20Z (168h0m0s)
+Uptime SLO: 99.9%, from the uptime_slo_999 tag
+Error budget: 10m4.8s = 168h0m0s x (100% - 99.9%)
+
+STATUS  FROM                  TO                    UP        DOWN   BUDGET AVAILABLE
+up      2023-11-07T22:13:20Z  2023-11-12T22:13:20Z  120h0m0s  0s     10m4.8s
+down    2023-11-12T22:13:20Z  2023-11-12T22:28:20Z  0s        15m0s  -4m55.2s
+up      2023-11-12T22:28:20Z  2023-11-14T22:13:20Z  47h45m0s  0s     -4m55.2s
+
+pingdom_up_seconds                                603900
+pingdom_down_seconds                              900
+pingdom_uptime_slo_error_budget_total_seconds     604.7999999999656
+pingdom_uptime_slo_error_budget_available_seconds -295.2000000000344
+`, buf.String())
+}
+
+func TestExplainHandler(t *testing.T) {
+	handler := explainHandler(newExplainTestCollector(t))
+
+	for _, tc := range []struct {
+		query          string
+		expectedStatus int
+		expectedType   string
+	}{
+		{"check=1", http.StatusOK, "application/json"},
+		{"check=1&format=text", http.StatusOK, "text/plain; charset=utf-8"},
+		{"check=4", http.StatusNotFound, "text/plain; charset=utf-8"},
+		{"check=api", http.StatusBadRequest, "text/plain; charset=utf-8"},
+		{"", http.StatusBadRequest, "text/plain; charset=utf-8"},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/explain?"+tc.query, nil))
+		assert.Equal(t, tc.expectedStatus, rec.Code, tc.query)
+		assert.Equal(t, tc.expectedType, rec.Header().Get("Content-Type"), tc.query)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/explain?check=1", nil))
+	var e explanation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &e))
+	assert.Equal(t, 1, e.CheckID)
+	assert.InDelta(t, -295.2, e.ErrorBudgetAvailableSeconds, 1e-6)
+}
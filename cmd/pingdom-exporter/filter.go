@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+// checkFilter selects the checks to be exported. A check is selected if it
+// matches any of the include selectors, or there are none, and doesn't match
+// any of the exclude selectors. A nil filter selects all checks.
+type checkFilter struct {
+	include []checkSelector
+	exclude []checkSelector
+}
+
+// newCheckFilter parses the given include and exclude selector expressions.
+func newCheckFilter(include, exclude []string) (*checkFilter, error) {
+	f := &checkFilter{}
+
+	for _, expr := range include {
+		s, err := parseCheckSelector(expr)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, s)
+	}
+
+	for _, expr := range exclude {
+		s, err := parseCheckSelector(expr)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, s)
+	}
+
+	return f, nil
+}
+
+// Match returns true if the check is selected by the filter.
+func (f *checkFilter) Match(check pingdom.CheckResponse) bool {
+	if f == nil {
+		return true
+	}
+
+	included := len(f.include) == 0
+	for _, s := range f.include {
+		if s.Match(check) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, s := range f.exclude {
+		if s.Match(check) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkSelector is a list of matchers that must all match a check, written as
+// comma separated field/value pairs, i.e. `tag=payments,name=~"API.*"`.
+type checkSelector []checkMatcher
+
+// Match returns true if all the matchers match the check.
+func (s checkSelector) Match(check pingdom.CheckResponse) bool {
+	for _, m := range s {
+		if !m.Match(check) {
+			return false
+		}
+	}
+	return true
+}
+
+type matchOp string
+
+const (
+	matchEqual     matchOp = "="
+	matchNotEqual  matchOp = "!="
+	matchRegexp    matchOp = "=~"
+	matchNotRegexp matchOp = "!~"
+)
+
+// checkFields maps the fields that can be used in selectors to the values of
+// a check. Fields with multiple values, i.e. tags, match if any value matches.
+var checkFields = map[string]func(check pingdom.CheckResponse) []string{
+	"tag": func(check pingdom.CheckResponse) []string {
+		var values []string
+		for _, tag := range check.Tags {
+			values = append(values, tag.Name)
+		}
+		return values
+	},
+	"name": func(check pingdom.CheckResponse) []string {
+		return []string{check.Name}
+	},
+	"hostname": func(check pingdom.CheckResponse) []string {
+		return []string{check.Hostname}
+	},
+	"type": func(check pingdom.CheckResponse) []string {
+		return []string{check.Type.Name}
+	},
+	"paused": func(check pingdom.CheckResponse) []string {
+		return []string{strconv.FormatBool(check.Status == "paused")}
+	},
+	"team": func(check pingdom.CheckResponse) []string {
+		var values []string
+		for _, team := range check.Teams {
+			values = append(values, team.Name)
+		}
+		return append(values, tagValues(check, "team")...)
+	},
+	"severity": func(check pingdom.CheckResponse) []string {
+		var values []string
+		if check.SeverityLevel != "" {
+			values = append(values, check.SeverityLevel)
+		}
+		return append(values, tagValues(check, "severity")...)
+	},
+}
+
+// tagValues returns the values of the tags in the form "<key>:<value>" or
+// "<key>_<value>", i.e. "team:payments" or "severity_critical".
+func tagValues(check pingdom.CheckResponse, key string) []string {
+	var values []string
+	for _, tag := range check.Tags {
+		for _, sep := range []string{":", "_"} {
+			if value, ok := strings.CutPrefix(tag.Name, key+sep); ok && value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// checkMatcher matches a field of a check against a value. The = and !=
+// operators match glob patterns, i.e. `hostname=*.example.com`, while =~ and
+// !~ match fully anchored regular expressions.
+type checkMatcher struct {
+	field  string
+	op     matchOp
+	value  string
+	re     *regexp.Regexp
+	values func(check pingdom.CheckResponse) []string
+}
+
+// Match returns true if the matcher matches the check.
+func (m checkMatcher) Match(check pingdom.CheckResponse) bool {
+	matched := false
+	for _, value := range m.values(check) {
+		if m.re != nil {
+			matched = m.re.MatchString(value)
+		} else {
+			matched, _ = path.Match(m.value, value)
+		}
+		if matched {
+			break
+		}
+	}
+
+	if m.op == matchNotEqual || m.op == matchNotRegexp {
+		return !matched
+	}
+	return matched
+}
+
+// parseCheckSelector parses a selector expression, i.e.
+// `tag=payments,hostname=*.example.com,name!~"(?i).*staging.*"`. Values
+// containing commas or spaces must be double quoted.
+func parseCheckSelector(expr string) (checkSelector, error) {
+	var selector checkSelector
+	rest := strings.TrimSpace(expr)
+
+	for rest != "" {
+		m, remaining, err := parseCheckMatcher(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid check selector %q: %w", expr, err)
+		}
+		selector = append(selector, m)
+
+		rest = strings.TrimSpace(remaining)
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("invalid check selector %q: expected comma before %q", expr, rest)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("invalid check selector %q: no matchers", expr)
+	}
+
+	return selector, nil
+}
+
+// parseCheckMatcher parses the matcher at the start of s, returning the
+// remaining input.
+func parseCheckMatcher(s string) (checkMatcher, string, error) {
+	i := strings.IndexAny(s, "=!")
+	if i < 0 {
+		return checkMatcher{}, "", fmt.Errorf("missing operator in %q", s)
+	}
+
+	m := checkMatcher{field: strings.TrimSpace(s[:i])}
+	values, ok := checkFields[m.field]
+	if !ok {
+		return checkMatcher{}, "", fmt.Errorf("unknown field %q", m.field)
+	}
+	m.values = values
+
+	s = s[i:]
+	for _, op := range []matchOp{matchRegexp, matchNotRegexp, matchNotEqual, matchEqual} {
+		if strings.HasPrefix(s, string(op)) {
+			m.op = op
+			s = strings.TrimSpace(s[len(op):])
+			break
+		}
+	}
+	if m.op == "" {
+		return checkMatcher{}, "", fmt.Errorf("invalid operator in %q", s)
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return checkMatcher{}, "", fmt.Errorf("invalid quoted value in %q", s)
+		}
+		m.value, _ = strconv.Unquote(quoted)
+		s = s[len(quoted):]
+	} else {
+		end := strings.IndexByte(s, ',')
+		if end < 0 {
+			end = len(s)
+		}
+		m.value = strings.TrimSpace(s[:end])
+		s = s[end:]
+	}
+
+	switch m.op {
+	case matchRegexp, matchNotRegexp:
+		re, err := regexp.Compile("^(?:" + m.value + ")$")
+		if err != nil {
+			return checkMatcher{}, "", fmt.Errorf("invalid regular expression for %s: %w", m.field, err)
+		}
+		m.re = re
+	default:
+		if _, err := path.Match(m.value, ""); err != nil {
+			return checkMatcher{}, "", fmt.Errorf("invalid pattern for %s: %w", m.field, err)
+		}
+	}
+
+	return m, s, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCheckSelector(t *testing.T) {
+	testCases := []struct {
+		expr        string
+		expectedErr string
+	}{
+		{expr: "tag=payments"},
+		{expr: ` name =~ "API, v2.*" , hostname!=*.internal `},
+		{expr: `type!~"ping|dns",paused=false,team=payments,severity=critical`},
+		{expr: "", expectedErr: `invalid check selector "": no matchers`},
+		{expr: "tag", expectedErr: `invalid check selector "tag": missing operator in "tag"`},
+		{expr: "owner=me", expectedErr: `invalid check selector "owner=me": unknown field "owner"`},
+		{expr: "tag!payments", expectedErr: `invalid check selector "tag!payments": invalid operator in "!payments"`},
+		{expr: `name="api`, expectedErr: `invalid check selector "name=\"api": invalid quoted value in "\"api"`},
+		{expr: `name="api" tag=x`, expectedErr: `invalid check selector "name=\"api\" tag=x": expected comma before "tag=x"`},
+		{expr: "name=~(", expectedErr: "invalid check selector \"name=~(\": invalid regular expression for name: error parsing regexp: missing closing ): `^(?:()$`"},
+		{expr: "hostname=[", expectedErr: `invalid check selector "hostname=[": invalid pattern for hostname: syntax error in pattern`},
+	}
+
+	for _, testCase := range testCases {
+		_, err := parseCheckSelector(testCase.expr)
+		if testCase.expectedErr == "" {
+			assert.NoError(t, err, testCase.expr)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr, testCase.expr)
+		}
+	}
+}
+
+func TestCheckFilterMatch(t *testing.T) {
+	api := testCheck(1, "Payments API", "up", "team:payments", "severity_critical")
+	api.Hostname = "api.example.com"
+	web := testCheck(2, "Website", "paused", "team_frontend", "staging")
+	web.Hostname = "www.example.org"
+	dns := testCheck(3, "DNS", "down")
+	dns.Hostname = "ns.example.net"
+	dns.Type = pingdom.CheckResponseType{Name: "dns"}
+	cdn := testCheck(4, "CDN", "up")
+	cdn.Teams = []pingdom.CheckTeamResponse{{ID: 1, Name: "Ops | Infra"}}
+	cdn.SeverityLevel = "HIGH"
+
+	testCases := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []int
+	}{
+		{name: "no selectors", expected: []int{1, 2, 3, 4}},
+		{name: "tag", include: []string{"tag=staging"}, expected: []int{2}},
+		{name: "tag glob", include: []string{"tag=team*"}, expected: []int{1, 2}},
+		{name: "name regexp", include: []string{`name=~"(?i)payments.*"`}, expected: []int{1}},
+		{name: "hostname glob", include: []string{"hostname=*.example.com"}, expected: []int{1, 4}},
+		{name: "type", exclude: []string{"type=dns"}, expected: []int{1, 2, 4}},
+		{name: "team", include: []string{"team=payments", "team=frontend"}, expected: []int{1, 2}},
+		{name: "pingdom team", include: []string{`team="Ops | Infra"`}, expected: []int{4}},
+		{name: "no team", include: []string{"team!~.+"}, expected: []int{3}},
+		{name: "paused", exclude: []string{"paused=true"}, expected: []int{1, 3, 4}},
+		{name: "severity", include: []string{"severity=critical"}, expected: []int{1}},
+		{name: "pingdom severity", include: []string{"severity=HIGH"}, expected: []int{4}},
+		{name: "all matchers", include: []string{"team=payments,paused=false,type=http"}, expected: []int{1}},
+		{name: "include and exclude", include: []string{"hostname=*.example.*"}, exclude: []string{"tag=staging"}, expected: []int{1, 3, 4}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			filter, err := newCheckFilter(testCase.include, testCase.exclude)
+			require.NoError(t, err)
+
+			var matched []int
+			for _, check := range []pingdom.CheckResponse{api, web, dns, cdn} {
+				if filter.Match(check) {
+					matched = append(matched, check.ID)
+				}
+			}
+			assert.Equal(t, testCase.expected, matched)
+		})
+	}
+
+	var filter *checkFilter
+	assert.True(t, filter.Match(api))
+}
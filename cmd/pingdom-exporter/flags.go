@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// activeFlags are the flags parsed from the command line, which are the ones
+// of the command being run, if any.
+var activeFlags = flag.CommandLine
+
+// isFlagSet returns true if the flag with the given name was set via the
+// command line.
+func isFlagSet(name string) bool {
+	set := false
+	activeFlags.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// stringSliceValue is a flag.Value that can be set multiple times, collecting
+// all the given values. The default values are replaced by the first value
+// set via the command line.
+type stringSliceValue struct {
+	values *[]string
+	set    bool
+}
+
+func newStringSliceValue(values *[]string, defaults ...string) *stringSliceValue {
+	*values = defaults
+	return &stringSliceValue{values: values}
+}
+
+func (s *stringSliceValue) String() string {
+	if s == nil || s.values == nil {
+		return ""
+	}
+	return strings.Join(*s.values, ",")
+}
+
+func (s *stringSliceValue) Set(value string) error {
+	if !s.set {
+		*s.values = nil
+		s.set = true
+	}
+	*s.values = append(*s.values, value)
+	return nil
+}
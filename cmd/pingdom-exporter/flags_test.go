@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringSliceValue(t *testing.T) {
+	var values []string
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(newStringSliceValue(&values, ":9158"), "address", "")
+
+	assert.NoError(t, fs.Parse(nil))
+	assert.Equal(t, []string{":9158"}, values)
+
+	assert.NoError(t, fs.Parse([]string{"-address", ":8080", "-address", "localhost:9090"}))
+	assert.Equal(t, []string{":8080", "localhost:9090"}, values)
+	assert.Equal(t, ":8080,localhost:9090", fs.Lookup("address").Value.String())
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// defaultForecastWindow is the default recent period the burn rate of the
+// error budget is computed over.
+const defaultForecastWindow = 24 * time.Hour
+
+// errorBudgetForecast is the forecast of the error budget of a check, given
+// its outages within the outage check period.
+type errorBudgetForecast struct {
+	// burnRate is the down time per second within the forecast window.
+	burnRate float64
+
+	// projectedAvailable is the error budget available, in seconds, once the
+	// outages within the current outage check period have rolled out of it,
+	// given the burn rate.
+	projectedAvailable float64
+
+	// exhaustion is when the available error budget is forecast to reach
+	// zero, or the zero time if it isn't within the next outage check
+	// period. It's now when the error budget is already exhausted.
+	exhaustion time.Time
+}
+
+// forecastErrorBudget forecasts the error budget of a check from its down
+// intervals within the outage check period ending now. As the period is a
+// rolling window, the outages within it roll out over time, while new down
+// time accrues at the burn rate of the last forecast window.
+func forecastErrorBudget(downIntervals []interval, now time.Time, period, window time.Duration, errorBudget float64) errorBudgetForecast {
+	start, end := now.Add(-period).Unix(), now.Unix()
+	window = min(window, period)
+
+	var clipped []interval
+	for _, i := range downIntervals {
+		clipped = append(clipped, interval{max(i.from, start), min(i.to, end)})
+	}
+	clipped = mergeIntervals(clipped)
+
+	f := errorBudgetForecast{
+		burnRate: downTimeWithin(clipped, now.Add(-window).Unix(), end) / window.Seconds(),
+	}
+
+	// available returns the error budget available in t seconds
+	available := func(t int64) float64 {
+		return errorBudget - downTimeWithin(clipped, start+t, end) - f.burnRate*float64(t)
+	}
+
+	// The available error budget is linear between the times the outages
+	// start and end rolling out of the period, so it reaches zero between
+	// two of them
+	periodSeconds := end - start
+	times := []int64{0, periodSeconds}
+	for _, i := range clipped {
+		times = append(times, i.from-start, i.to-start)
+	}
+	slices.Sort(times)
+	times = slices.Compact(times)
+
+	f.projectedAvailable = available(periodSeconds)
+
+	if available(0) <= 0 {
+		f.exhaustion = now
+		return f
+	}
+	for j := 1; j < len(times); j++ {
+		before, after := available(times[j-1]), available(times[j])
+		if before > 0 && after <= 0 {
+			t := float64(times[j-1]) + before*float64(times[j]-times[j-1])/(before-after)
+			f.exhaustion = now.Add(time.Duration(t * float64(time.Second)))
+			break
+		}
+	}
+
+	return f
+}
+
+// downTimeWithin returns the down time within the given period, in seconds,
+// given sorted non-overlapping down intervals.
+func downTimeWithin(intervals []interval, from, to int64) float64 {
+	var downTime int64
+	for _, i := range intervals {
+		if overlap := min(i.to, to) - max(i.from, from); overlap > 0 {
+			downTime += overlap
+		}
+	}
+	return float64(downTime)
+}
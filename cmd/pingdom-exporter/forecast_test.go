@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForecastErrorBudget(t *testing.T) {
+	now := testNow.Unix()
+	ago := func(seconds int64) int64 { return now - seconds }
+
+	testCases := []struct {
+		name               string
+		downIntervals      []interval
+		burnRate           float64
+		projectedAvailable float64
+		exhaustion         time.Time
+	}{
+		{
+			name:               "no outages",
+			projectedAvailable: 100,
+		},
+		{
+			// Without recent outages, the budget only grows as the old
+			// ones roll out, the one before the period being clipped
+			name:               "old outages",
+			downIntervals:      []interval{{ago(1100), ago(950)}, {ago(800), ago(780)}},
+			projectedAvailable: 100,
+		},
+		{
+			// 30s left, then 60s once the old outage rolled out after 100s,
+			// burning 0.2s per second until exhausted after 400s
+			name:               "recent outages",
+			downIntervals:      []interval{{ago(950), ago(900)}, {ago(50), ago(30)}},
+			burnRate:           0.2,
+			projectedAvailable: -100,
+			exhaustion:         testNow.Add(400 * time.Second),
+		},
+		{
+			name:               "exhausted",
+			downIntervals:      []interval{{ago(500), ago(350)}, {ago(90), ago(80)}},
+			burnRate:           0.1,
+			projectedAvailable: 0,
+			exhaustion:         testNow,
+		},
+	}
+
+	for _, testCase := range testCases {
+		f := forecastErrorBudget(testCase.downIntervals, testNow, 1000*time.Second, 100*time.Second, 100)
+		assert.InDelta(t, testCase.burnRate, f.burnRate, 1e-9, testCase.name)
+		assert.InDelta(t, testCase.projectedAvailable, f.projectedAvailable, 1e-9, testCase.name)
+		assert.True(t, testCase.exhaustion.Equal(f.exhaustion), "%s: expected %s, got %s", testCase.name, testCase.exhaustion, f.exhaustion)
+	}
+
+	// The forecast window is at most the outage check period
+	f := forecastErrorBudget([]interval{{ago(500), ago(400)}}, testNow, 1000*time.Second, time.Hour, 200)
+	assert.InDelta(t, 0.1, f.burnRate, 1e-9)
+	assert.True(t, f.exhaustion.IsZero())
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+var (
+	checksFormat  string
+	outagesFormat string
+	outagesSince  string
+)
+
+var checksCommand = &command{
+	name: "checks",
+	args: "list",
+	help: "list the checks matching the filters of the exporter, with their status, uptime SLO and whether they are ignored",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&checksFormat, "format", "table", "output format (one of: table, json)")
+	},
+	run: runChecks,
+}
+
+var outagesCommand = &command{
+	name: "outages",
+	args: "<check id>",
+	help: "list the outage states of a check, with its up and down time, as used to compute its error budget",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&outagesSince, "since", "", "start of the period to list the outage states of, as a RFC 3339 timestamp, a date or a duration ago, i.e. 2024-01-01 or 7d (defaults to the outage check period)")
+		fs.StringVar(&outagesFormat, "format", "table", "output format (one of: table, json)")
+	},
+	run: runOutages,
+}
+
+// checkInfo is a check as listed by the checks command.
+type checkInfo struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	Hostname  string  `json:"hostname"`
+	Status    string  `json:"status"`
+	Tags      string  `json:"tags"`
+	UptimeSLO float64 `json:"uptime_slo"`
+	Ignored   bool    `json:"ignored"`
+}
+
+func runChecks(_ context.Context, c *commandContext) error {
+	if len(c.args) != 1 || c.args[0] != "list" {
+		return errors.New(`expected the "list" command`)
+	}
+	if err := validateOutputFormat(checksFormat); err != nil {
+		return err
+	}
+
+	checks, err := c.collector.matchingChecks()
+	if err != nil {
+		return fmt.Errorf("cannot retrieve checks: %w", err)
+	}
+
+	infos := []checkInfo{}
+	for _, check := range checks {
+		infos = append(infos, checkInfo{
+			ID:        check.ID,
+			Name:      check.Name,
+			Hostname:  check.Hostname,
+			Status:    check.Status,
+			Tags:      check.TagsString(),
+			UptimeSLO: c.collector.uptimeSLO(check).Value,
+			Ignored:   check.HasIgnoreTag(),
+		})
+	}
+
+	if checksFormat == "json" {
+		return writeJSON(c.stdout, infos)
+	}
+	return writeChecksTable(c.stdout, infos)
+}
+
+func writeChecksTable(w io.Writer, infos []checkInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tHOSTNAME\tSTATUS\tUPTIME SLO\tIGNORED\tTAGS")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s%%\t%t\t%s\n", info.ID, info.Name, info.Hostname, info.Status, formatFloat(info.UptimeSLO), info.Ignored, info.Tags)
+	}
+	return tw.Flush()
+}
+
+// outageList is the outage states of a check within a period of time, as
+// listed by the outages command.
+type outageList struct {
+	CheckID         int           `json:"check_id"`
+	From            time.Time     `json:"from"`
+	To              time.Time     `json:"to"`
+	States          []outageState `json:"states"`
+	UpTimeSeconds   float64       `json:"up_seconds"`
+	DownTimeSeconds float64       `json:"down_seconds"`
+}
+
+type outageState struct {
+	Status          string    `json:"status"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+func runOutages(_ context.Context, c *commandContext) error {
+	if len(c.args) != 1 {
+		return errors.New("expected a check id")
+	}
+	checkID, err := strconv.Atoi(c.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid check id %q", c.args[0])
+	}
+	if err := validateOutputFormat(outagesFormat); err != nil {
+		return err
+	}
+
+	from, to, err := parsePeriod(outagesSince, "", c.collector.now(), c.collector.outageCheckPeriod)
+	if err != nil {
+		return err
+	}
+
+	list, err := newOutageList(c.collector.client, checkID, from, to)
+	if err != nil {
+		return err
+	}
+
+	if outagesFormat == "json" {
+		return writeJSON(c.stdout, list)
+	}
+	return writeOutagesTable(c.stdout, list)
+}
+
+// newOutageList retrieves the outage states of the check within the period.
+// The up and down time are the ones the exporter computes the error budget
+// of the check from, when the period is the outage check period.
+func newOutageList(client *pingdom.Client, checkID int, from, to time.Time) (*outageList, error) {
+	states, err := client.OutageSummary.List(checkID, map[string]string{
+		"from": strconv.FormatInt(from.Unix(), 10),
+		"to":   strconv.FormatInt(to.Unix(), 10),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve outages of check %d: %w", checkID, err)
+	}
+
+	list := &outageList{CheckID: checkID, From: from.UTC(), To: to.UTC(), States: []outageState{}}
+	list.UpTimeSeconds, list.DownTimeSeconds = outageTimes(states, from, to)
+	for _, state := range states {
+		list.States = append(list.States, outageState{
+			Status:          state.Status,
+			From:            time.Unix(state.FromTime, 0).UTC(),
+			To:              time.Unix(state.ToTime, 0).UTC(),
+			DurationSeconds: float64(state.ToTime - state.FromTime),
+		})
+	}
+	return list, nil
+}
+
+func writeOutagesTable(w io.Writer, list *outageList) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tFROM\tTO\tDURATION")
+	for _, state := range list.States {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", state.Status, state.From.Format(time.RFC3339), state.To.Format(time.RFC3339), time.Duration(state.DurationSeconds)*time.Second)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nUp %s, down %s from %s to %s.\n",
+		time.Duration(list.UpTimeSeconds)*time.Second,
+		time.Duration(list.DownTimeSeconds)*time.Second,
+		list.From.Format(time.RFC3339),
+		list.To.Format(time.RFC3339),
+	)
+	return err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInspectTestContext(t *testing.T, args ...string) (*commandContext, *bytes.Buffer) {
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			testCheck(1, "api", "up", "uptime_slo_999"),
+			testCheck(2, "legacy", "paused", "pingdom_exporter_ignored"),
+			testCheck(3, "staging", "down"),
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: testOutages(10 * time.Minute)},
+	})
+	t.Cleanup(server.Close)
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.now = func() time.Time { return testNow }
+	collector.filter, err = newCheckFilter(nil, []string{"name=staging"})
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	return &commandContext{collector: collector, args: args, stdout: &stdout}, &stdout
+}
+
+func TestChecksCommand(t *testing.T) {
+	c, stdout := newInspectTestContext(t, "list")
+
+	checksFormat = "table"
+	require.NoError(t, runChecks(context.Background(), c))
+	assert.Equal(t, `ID  NAME    HOSTNAME            STATUS  UPTIME SLO  IGNORED  TAGS
+1   api     api.example.com     up      99.9%       false    uptime_slo_999
+2   legacy  legacy.example.com  paused  99%         true     pingdom_exporter_ignored
+`, stdout.String())
+
+	stdout.Reset()
+	checksFormat = "json"
+	require.NoError(t, runChecks(context.Background(), c))
+	var infos []checkInfo
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &infos))
+	assert.Equal(t, []checkInfo{
+		{ID: 1, Name: "api", Hostname: "api.example.com", Status: "up", Tags: "uptime_slo_999", UptimeSLO: 99.9},
+		{ID: 2, Name: "legacy", Hostname: "legacy.example.com", Status: "paused", Tags: "pingdom_exporter_ignored", UptimeSLO: 99, Ignored: true},
+	}, infos)
+
+	c.args = nil
+	assert.EqualError(t, runChecks(context.Background(), c), `expected the "list" command`)
+}
+
+func TestOutagesCommand(t *testing.T) {
+	c, stdout := newInspectTestContext(t, "1")
+
+	outagesSince = "3d"
+	outagesFormat = "table"
+	require.NoError(t, runOutages(context.Background(), c))
+	assert.Equal(t, `STATUS  FROM                  TO                    DURATION
+up      2023-11-11T22:13:20Z  2023-11-12T22:13:20Z  24h0m0s
+down    2023-11-12T22:13:20Z  2023-11-12T22:23:20Z  10m0s
+up      2023-11-12T22:23:20Z  2023-11-14T22:13:20Z  47h50m0s
+
+Up 71h50m0s, down 10m0s from 2023-11-11T22:13:20Z to 2023-11-14T22:13:20Z.
+`, stdout.String())
+
+	stdout.Reset()
+	outagesSince = ""
+	outagesFormat = "json"
+	require.NoError(t, runOutages(context.Background(), c))
+	var list outageList
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &list))
+	assert.Equal(t, 1, list.CheckID)
+	assert.True(t, testNow.Add(-7*24*time.Hour).Equal(list.From))
+	assert.Len(t, list.States, 3)
+	assert.Equal(t, 600.0, list.DownTimeSeconds)
+	assert.Equal(t, 7*24*3600.0-600.0, list.UpTimeSeconds)
+
+	c.args = []string{"api"}
+	assert.EqualError(t, runOutages(context.Background(), c), `invalid check id "api"`)
+
+	outagesFormat = "yaml"
+	c.args = []string{"1"}
+	assert.EqualError(t, runOutages(context.Background(), c), `invalid output format "yaml"`)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+// labelNameRegexp matches valid Prometheus label names.
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// tagLabelRule extracts the value of a label from the tags of a check, either
+// from the tags with the given prefix, i.e. "team:payments" with the "team:"
+// prefix, or from the tags matching a regular expression, i.e. "env_prod" with
+// "env_(.+)", whose first capturing group, if any, is the value.
+type tagLabelRule struct {
+	Label  string `yaml:"label"`
+	Prefix string `yaml:"prefix"`
+	Regex  string `yaml:"regex"`
+
+	re *regexp.Regexp
+}
+
+// parseTagLabelRule parses a rule in the form "<label>=<prefix or regex>", as
+// given via the -tags.label-prefix and -tags.label-regex flags.
+func parseTagLabelRule(s string, regex bool) (tagLabelRule, error) {
+	label, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return tagLabelRule{}, fmt.Errorf("invalid tag label rule %q, expected <label>=<value>", s)
+	}
+
+	if regex {
+		return tagLabelRule{Label: label, Regex: value}, nil
+	}
+	return tagLabelRule{Label: label, Prefix: value}, nil
+}
+
+// value returns the label value extracted from the given tag, if it matches.
+func (r *tagLabelRule) value(tag string) (string, bool) {
+	if r.re == nil {
+		value, ok := strings.CutPrefix(tag, r.Prefix)
+		return value, ok && value != ""
+	}
+
+	matches := r.re.FindStringSubmatch(tag)
+	switch {
+	case matches == nil:
+		return "", false
+	case len(matches) > 1:
+		return matches[1], matches[1] != ""
+	default:
+		return matches[0], true
+	}
+}
+
+// tagLabeler computes the labels derived from the tags of a check, which are
+// added to the per-check metrics. A nil tagLabeler only adds the raw "tags"
+// label, with all the tags of the check separated by commas.
+type tagLabeler struct {
+	rules   []tagLabelRule
+	names   []string
+	rawTags bool
+}
+
+// newTagLabeler validates the given rules, which must not use the labels the
+// exporter already sets. Several rules may set the same label, in which case
+// the values extracted by all of them are merged.
+func newTagLabeler(rules []tagLabelRule, rawTags bool, reserved []string) (*tagLabeler, error) {
+	l := &tagLabeler{rawTags: rawTags}
+	if rawTags {
+		reserved = append(reserved, "tags")
+	}
+
+	for _, rule := range rules {
+		if !labelNameRegexp.MatchString(rule.Label) || strings.HasPrefix(rule.Label, "__") {
+			return nil, fmt.Errorf("invalid label name %q in tag label rule", rule.Label)
+		}
+		if slices.Contains(reserved, rule.Label) {
+			return nil, fmt.Errorf("tag label rule cannot set the %q label, which is already set by the exporter", rule.Label)
+		}
+
+		switch {
+		case rule.Prefix != "" && rule.Regex != "":
+			return nil, fmt.Errorf("tag label rule for %q must have either a prefix or a regex, not both", rule.Label)
+		case rule.Prefix == "" && rule.Regex == "":
+			return nil, fmt.Errorf("tag label rule for %q must have a prefix or a regex", rule.Label)
+		case rule.Regex != "":
+			re, err := regexp.Compile("^(?:" + rule.Regex + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex in tag label rule for %q: %w", rule.Label, err)
+			}
+			rule.re = re
+		}
+
+		l.rules = append(l.rules, rule)
+		if !slices.Contains(l.names, rule.Label) {
+			l.names = append(l.names, rule.Label)
+		}
+	}
+
+	return l, nil
+}
+
+// labelNames returns the names of the labels derived from the tags.
+func (l *tagLabeler) labelNames() []string {
+	if l == nil {
+		return []string{"tags"}
+	}
+
+	names := slices.Clone(l.names)
+	if l.rawTags {
+		names = append(names, "tags")
+	}
+	return names
+}
+
+// labelValues returns the values of the labels derived from the tags of the
+// check, in the same order as labelNames. Labels without any matching tag
+// are empty, while multiple matching tags are sorted and separated by commas.
+func (l *tagLabeler) labelValues(check pingdom.CheckResponse) []string {
+	if l == nil {
+		return []string{check.TagsString()}
+	}
+
+	values := make([]string, 0, len(l.names)+1)
+	for _, name := range l.names {
+		var matched []string
+		for _, rule := range l.rules {
+			if rule.Label != name {
+				continue
+			}
+			for _, tag := range check.Tags {
+				if value, ok := rule.value(tag.Name); ok && !slices.Contains(matched, value) {
+					matched = append(matched, value)
+				}
+			}
+		}
+		slices.Sort(matched)
+		values = append(values, strings.Join(matched, ","))
+	}
+
+	if l.rawTags {
+		values = append(values, check.TagsString())
+	}
+	return values
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTagLabelRule(t *testing.T) {
+	rule, err := parseTagLabelRule("team=team:", false)
+	assert.NoError(t, err)
+	assert.Equal(t, tagLabelRule{Label: "team", Prefix: "team:"}, rule)
+
+	rule, err = parseTagLabelRule("env=env_(.+)", true)
+	assert.NoError(t, err)
+	assert.Equal(t, tagLabelRule{Label: "env", Regex: "env_(.+)"}, rule)
+
+	_, err = parseTagLabelRule("team", false)
+	assert.EqualError(t, err, `invalid tag label rule "team", expected <label>=<value>`)
+}
+
+func TestNewTagLabeler(t *testing.T) {
+	testCases := []struct {
+		rule        tagLabelRule
+		expectedErr string
+	}{
+		{rule: tagLabelRule{Label: "team", Prefix: "team:"}},
+		{rule: tagLabelRule{Label: "env", Regex: "env_(.+)"}},
+		{rule: tagLabelRule{Label: "team-name", Prefix: "team:"}, expectedErr: `invalid label name "team-name" in tag label rule`},
+		{rule: tagLabelRule{Label: "__name__", Prefix: "name:"}, expectedErr: `invalid label name "__name__" in tag label rule`},
+		{rule: tagLabelRule{Label: "hostname", Prefix: "host:"}, expectedErr: `tag label rule cannot set the "hostname" label, which is already set by the exporter`},
+		{rule: tagLabelRule{Label: "tags", Prefix: "tags:"}, expectedErr: `tag label rule cannot set the "tags" label, which is already set by the exporter`},
+		{rule: tagLabelRule{Label: "team"}, expectedErr: `tag label rule for "team" must have a prefix or a regex`},
+		{rule: tagLabelRule{Label: "team", Prefix: "team:", Regex: "team_(.+)"}, expectedErr: `tag label rule for "team" must have either a prefix or a regex, not both`},
+		{rule: tagLabelRule{Label: "env", Regex: "env_(.+"}, expectedErr: "invalid regex in tag label rule for \"env\": error parsing regexp: missing closing ): `^(?:env_(.+)$`"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := newTagLabeler([]tagLabelRule{testCase.rule}, true, checkMetricLabels)
+		if testCase.expectedErr == "" {
+			assert.NoError(t, err, testCase.rule.Label)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr, testCase.rule.Label)
+		}
+	}
+}
+
+func TestTagLabeler(t *testing.T) {
+	check := testCheck(1, "api", "up", "team:payments", "env_prod", "team_checkout", "severity_critical")
+
+	labeler, err := newTagLabeler([]tagLabelRule{
+		{Label: "team", Prefix: "team:"},
+		{Label: "env", Regex: "env_(.+)"},
+		{Label: "team", Prefix: "team_"},
+		{Label: "critical", Regex: "severity_critical"},
+		{Label: "region", Prefix: "region:"},
+	}, false, checkMetricLabels)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"team", "env", "critical", "region"}, labeler.labelNames())
+	assert.Equal(t, []string{"checkout,payments", "prod", "severity_critical", ""}, labeler.labelValues(check))
+
+	labeler.rawTags = true
+	assert.Equal(t, []string{"team", "env", "critical", "region", "tags"}, labeler.labelNames())
+	assert.Equal(t, "team:payments,env_prod,team_checkout,severity_critical", labeler.labelValues(check)[4])
+
+	labeler = nil
+	assert.Equal(t, []string{"tags"}, labeler.labelNames())
+	assert.Equal(t, []string{"team:payments,env_prod,team_checkout,severity_critical"}, labeler.labelValues(check))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+// latencyWindow holds the response times of the successful test results of a
+// check within the outage check period, to evaluate its latency SLOs.
+type latencyWindow struct {
+	cursor resultCursor
+
+	// samples are the response times of the results, oldest first.
+	samples []latencySample
+}
+
+type latencySample struct {
+	time         int64
+	responseTime time.Duration
+}
+
+func newLatencyWindow(from time.Time) *latencyWindow {
+	return &latencyWindow{cursor: newResultCursor(from)}
+}
+
+// observe adds the results newer than the ones already observed and drops the
+// ones before the beginning of the window. As for the response time
+// histogram, failed tests are not observed: they count against the uptime
+// SLO instead.
+func (w *latencyWindow) observe(results []pingdom.ResultsResponseResult, from time.Time) {
+	// Results are returned newest first
+	for i := len(results) - 1; i >= 0; i-- {
+		result := results[i]
+		if !w.cursor.next(result) {
+			continue
+		}
+
+		if result.Status == "up" {
+			w.samples = append(w.samples, latencySample{result.Time, time.Duration(result.ResponseTime) * time.Millisecond})
+		}
+	}
+
+	i := 0
+	for i < len(w.samples) && w.samples[i].time < from.Unix() {
+		i++
+	}
+	w.samples = w.samples[i:]
+}
+
+// events returns the number of results within the window which responded
+// within the threshold, and the total number of results.
+func (w *latencyWindow) events(threshold time.Duration) (good, total float64) {
+	for _, sample := range w.samples {
+		if sample.responseTime <= threshold {
+			good++
+		}
+	}
+	return good, float64(len(w.samples))
+}
+
+// latencyErrorBudgetRemaining returns the ratio of the tests allowed to be
+// slower than the threshold of the latency SLO which are left, given the
+// number of good and total tests. It's 1 without tests, and negative once
+// more tests than allowed are slower.
+func latencyErrorBudgetRemaining(slo pingdom.LatencySLO, good, total float64) float64 {
+	allowed := total * (100.0 - slo.Objective) / 100.0
+	if allowed <= 0 {
+		if good < total {
+			return -1
+		}
+		return 1
+	}
+	return 1 - (total-good)/allowed
+}
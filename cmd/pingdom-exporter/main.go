@@ -1,18 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"strconv"
-	"sync"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/fixtures"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 )
 
 var (
@@ -20,315 +23,350 @@ var (
 	VERSION string
 
 	token             string
+	tokenFile         string
+	tokenFileRefresh  time.Duration
 	tags              string
+	baseURL           string
+	recordDir         string
+	replayDir         string
 	metricsPath       string
 	waitSeconds       int
 	port              int
 	outageCheckPeriod int
 	defaultUptimeSLO  float64
+	minUptimeSLO      float64
+	staleOutageMaxAge time.Duration
+	logLevel          string
+	logFormat         string
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	shutdownTimeout   time.Duration
+
+	configFile       string
+	includeChecks    []string
+	excludeChecks    []string
+	tagLabelPrefixes []string
+	tagLabelRegexes  []string
+	rawTagsLabel     bool
+	includeMetrics   []string
+	excludeMetrics   []string
+	includeLabels    []string
+	excludeLabels    []string
+	maxSeries        int
+	stableLabels     bool
+	collectResults   bool
+	collectEvents    bool
+	forecastWindow   time.Duration
+	resultURL        string
+
+	pushgatewayURL string
+	remoteWriteURL string
+	otlpEndpoint   string
+	otlpProtocol   string
+	otlpAccount    string
+	pushJob        string
+	pushInterval   time.Duration
+	pushOnce       bool
+
+	webListenAddresses []string
+	webSystemdSocket   bool
+	webConfigFile      string
+)
 
-	pingdomUpDesc = prometheus.NewDesc(
-		"pingdom_up",
-		"Whether the last pingdom scrape was successfull (1: up, 0: down).",
-		nil, nil,
-	)
+func init() {
+	flag.IntVar(&port, "port", 9158, "deprecated: port to listen on, use -web.listen-address instead")
+	flag.Var(newStringSliceValue(&webListenAddresses, ":9158"), "web.listen-address", "address on which to expose metrics and web interface; repeatable for multiple addresses (default :9158)")
+	flag.BoolVar(&webSystemdSocket, "web.systemd-socket", false, "use systemd socket activation listeners instead of port listeners (Linux only)")
+	flag.StringVar(&webConfigFile, "web.config.file", "", "path to a configuration file that can enable TLS or authentication, see https://github.com/prometheus/exporter-toolkit/blob/master/docs/web-configuration.md")
+	flag.IntVar(&outageCheckPeriod, "outage-check-period", 7, "time (in days) in which to retrieve outage data from the Pingdom API")
+	flag.Float64Var(&defaultUptimeSLO, "default-uptime-slo", 99.0, "default uptime SLO to be used when the check doesn't provide a valid uptime SLO tag (i.e. uptime_slo_999 to 99.9% uptime SLO)")
+	flag.Float64Var(&minUptimeSLO, "min-uptime-slo", defaultMinUptimeSLO, "minimum uptime SLO of a valid uptime SLO tag, so i.e. uptime_slo_9 isn't taken as a 9% uptime SLO; lower it to allow lower uptime SLOs, 0 accepts any above 0%")
+	flag.DurationVar(&staleOutageMaxAge, "stale-outage-max-age", 0, "maximum age of the last known good outage data of a check to be exported when the Pingdom API fails to return it (i.e. 1h); disabled by default")
+	flag.StringVar(&configFile, "config.file", "", "path to a YAML configuration file, i.e. with the checks to be exported")
+	flag.Var(newStringSliceValue(&includeChecks), "checks.include", "only export checks matching the given selector, i.e. 'tag=payments,hostname=*.example.com'; repeatable, checks matching any selector are exported")
+	flag.Var(newStringSliceValue(&excludeChecks), "checks.exclude", "do not export checks matching the given selector, i.e. 'name=~\"(?i).*staging.*\"'; repeatable")
+	flag.Var(newStringSliceValue(&tagLabelPrefixes), "tags.label-prefix", "add a label to the check metrics from the tags with the given prefix, in the form <label>=<prefix>, i.e. 'team=team:' to export the tag team:payments as team=\"payments\"; repeatable")
+	flag.Var(newStringSliceValue(&tagLabelRegexes), "tags.label-regex", "add a label to the check metrics from the tags matching the given regex, in the form <label>=<regex>, whose first capturing group is the value, i.e. 'env=env_(.+)' to export the tag env_prod as env=\"prod\"; repeatable")
+	flag.BoolVar(&rawTagsLabel, "tags.raw-label", true, "add the tags label, with all the tags of the check separated by commas, to the check metrics")
+	flag.Var(newStringSliceValue(&includeMetrics), "metrics.include", "only expose the metric families matching the given glob pattern, i.e. 'pingdom_uptime_*'; repeatable")
+	flag.Var(newStringSliceValue(&excludeMetrics), "metrics.exclude", "do not expose the metric families matching the given glob pattern, i.e. 'pingdom_uptime_response_time_seconds' or 'go_*'; repeatable")
+	flag.Var(newStringSliceValue(&includeLabels), "labels.include", "only expose the labels matching the given glob pattern; repeatable")
+	flag.Var(newStringSliceValue(&excludeLabels), "labels.exclude", "do not expose the labels matching the given glob pattern, i.e. 'hostname' or 'tags'; repeatable")
+	flag.IntVar(&maxSeries, "metrics.max-series", 0, "maximum number of series to expose per scrape, dropping the remaining ones; 0 disables the limit")
+	flag.BoolVar(&stableLabels, "metrics.stable-labels", false, "export the check status via pingdom_check_state{state=\"...\"} and without the status, paused and resolution labels, so status changes don't create new series")
+	flag.DurationVar(&forecastWindow, "forecast.window", defaultForecastWindow, "recent period the burn rate of the error budget is computed over, to forecast when it is exhausted; at most the outage check period")
+	flag.BoolVar(&collectResults, "results.enabled", false, "retrieve the raw test results of every check to export the pingdom_check_response_time_seconds histogram, with exemplars linking to the results; costs one more Pingdom API request per check and scrape")
+	flag.BoolVar(&collectEvents, "results.sli-events", false, "retrieve the raw test results of every check to export the pingdom_sli_good_events_total and pingdom_sli_total_events_total counters, for event-based SLIs; costs one more Pingdom API request per check and scrape, shared with -results.enabled")
+	flag.StringVar(&resultURL, "results.exemplar-url", defaultResultURLTemplate, "URL of the exemplars of the response time histogram, in which {check_id}, {probe_id} and {time} are replaced with the ones of the result")
+	flag.StringVar(&pushgatewayURL, "push.pushgateway-url", "", "push the metrics to the Pushgateway at the given URL instead of serving them, i.e. when the exporter cannot be scraped")
+	flag.StringVar(&remoteWriteURL, "push.remote-write-url", "", "push the metrics to the Prometheus remote write endpoint at the given URL instead of serving them, with the status and response time of the checks timestamped with the time of their last test")
+	flag.StringVar(&otlpEndpoint, "push.otlp-endpoint", "", "push the metrics via OTLP to the OpenTelemetry collector at the given URL instead of serving them, i.e. http://localhost:4318/v1/metrics for http/protobuf or http://localhost:4317 for grpc, with https enabling TLS")
+	flag.StringVar(&otlpProtocol, "push.otlp-protocol", otlpProtocolHTTP, "protocol of the OTLP endpoint (one of: http/protobuf, grpc)")
+	flag.StringVar(&otlpAccount, "push.otlp-account", "", "Pingdom account name, set as the pingdom.account resource attribute of the metrics pushed via OTLP")
+	flag.StringVar(&pushJob, "push.job", "pingdom", "job label of the pushed metrics")
+	flag.DurationVar(&pushInterval, "push.interval", time.Minute, "how often to push the metrics")
+	flag.BoolVar(&pushOnce, "push.once", false, "push the metrics once and exit, i.e. when running as a CronJob")
+	flag.StringVar(&metricsPath, "metrics-path", "/metrics", "path under which to expose metrics")
+	flag.StringVar(&tokenFile, "pingdom.token-file", os.Getenv("PINGDOM_API_TOKEN_FILE"), "file from which to read the Pingdom API token, re-read periodically to pick up a rotated token; takes precedence over the PINGDOM_API_TOKEN and PINGDOM_TOKEN environment variables (defaults to $PINGDOM_API_TOKEN_FILE)")
+	flag.DurationVar(&tokenFileRefresh, "pingdom.token-file-refresh-interval", time.Minute, "how often to re-read the Pingdom API token file")
+	flag.StringVar(&tags, "tags", "", "tag list separated by commas")
+	flag.StringVar(&baseURL, "pingdom-base-url", "", "base URL of the Pingdom API, i.e. to point the exporter to a pingdom-fake instance (defaults to the public Pingdom API)")
+	flag.StringVar(&recordDir, "pingdom-record-dir", "", "directory in which to record the Pingdom API responses as fixtures, with the API token and contact PII scrubbed")
+	flag.StringVar(&replayDir, "pingdom-replay-dir", "", "directory from which to replay previously recorded Pingdom API responses instead of calling the Pingdom API")
+	flag.DurationVar(&readTimeout, "read-timeout", 10*time.Second, "maximum duration for reading an entire HTTP request")
+	flag.DurationVar(&writeTimeout, "write-timeout", time.Minute, "maximum duration before timing out writes of an HTTP response, which must be greater than the scrape duration")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "maximum duration to wait for in-flight scrapes to complete when shutting down")
+	flag.StringVar(&logLevel, "log-level", "info", "only log messages with the given severity or above (one of: debug, info, warn, error)")
+	flag.StringVar(&logFormat, "log-format", "logfmt", "output format of log messages (one of: logfmt, json)")
+}
 
-	pingdomRateLimitRemainingRequestsDesc = prometheus.NewDesc(
-		"pingdom_rate_limit_remaining_requests",
-		"Tracks the remaining requests allowed before hitting the short-term or long-term rate limit in the Pingdom API.",
-		nil, nil,
-	)
+// newLogger returns a logger with the given level and output format.
+func newLogger(level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
 
-	pingdomOutageCheckPeriodDesc = prometheus.NewDesc(
-		"pingdom_slo_period_seconds",
-		"Outage check period, in seconds",
-		nil, nil,
-	)
+	opts := &slog.HandlerOptions{Level: lvl}
 
-	pingdomCheckStatusDesc = prometheus.NewDesc(
-		"pingdom_uptime_status",
-		"The current status of the check (1: up, 0: down)",
-		[]string{"id", "name", "hostname", "status", "resolution", "paused", "tags"}, nil,
-	)
+	switch format {
+	case "logfmt":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
 
-	pingdomCheckResponseTimeDesc = prometheus.NewDesc(
-		"pingdom_uptime_response_time_seconds",
-		"The response time of last test, in seconds",
-		[]string{"id", "name", "hostname", "status", "resolution", "paused", "tags"}, nil,
-	)
+	return nil, fmt.Errorf("invalid log format %q", format)
+}
 
-	pingdomOutagesDesc = prometheus.NewDesc(
-		"pingdom_outages_total",
-		"Number of outages within the outage check period",
-		[]string{"id", "name", "hostname", "tags"}, nil,
-	)
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(runCommand(cmd, os.Args[2:]))
+		}
+	}
 
-	pingdomCheckErrorBudgetDesc = prometheus.NewDesc(
-		"pingdom_uptime_slo_error_budget_total_seconds",
-		"Maximum number of allowed downtime, in seconds, according to the uptime SLO",
-		[]string{"id", "name", "hostname", "tags"}, nil,
-	)
+	flag.Usage = usage
+	flag.Parse()
 
-	pingdomCheckAvailableErrorBudgetDesc = prometheus.NewDesc(
-		"pingdom_uptime_slo_error_budget_available_seconds",
-		"Number of seconds of downtime we can still have without breaking the uptime SLO",
-		[]string{"id", "name", "hostname", "tags"}, nil,
-	)
+	logger, err := newLogger(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v, exiting\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
 
-	pingdomDownTimeDesc = prometheus.NewDesc(
-		"pingdom_down_seconds",
-		"Total down time within the outage check period, in seconds",
-		[]string{"id", "name", "hostname", "tags"}, nil,
-	)
+	registry := prometheus.NewPedanticRegistry()
 
-	pingdomUpTimeDesc = prometheus.NewDesc(
-		"pingdom_up_seconds",
-		"Total up time within the outage check period, in seconds",
-		[]string{"id", "name", "hostname", "tags"}, nil,
-	)
-)
+	collector, cfg, err := newCollectorFromFlags(logger, registry)
+	if err != nil {
+		logger.Error("Cannot set up the exporter, exiting", "err", err)
+		os.Exit(1)
+	}
 
-func init() {
-	flag.IntVar(&port, "port", 9158, "port to listen on")
-	flag.IntVar(&outageCheckPeriod, "outage-check-period", 7, "time (in days) in which to retrieve outage data from the Pingdom API")
-	flag.Float64Var(&defaultUptimeSLO, "default-uptime-slo", 99.0, "default uptime SLO to be used when the check doesn't provide a uptime SLO tag (i.e. uptime_slo_999 to 99.9% uptime SLO)")
-	flag.StringVar(&metricsPath, "metrics-path", "/metrics", "path under which to expose metrics")
-	flag.StringVar(&tags, "tags", "", "tag list separated by commas")
-}
+	selector, err := newSeriesSelectorFromFlags(cfg)
+	if err != nil {
+		logger.Error("Invalid metric selection, exiting", "err", err)
+		os.Exit(1)
+	}
 
-type pingdomCollector struct {
-	client *pingdom.Client
-}
+	collector.lastTestTimestamps = remoteWriteURL != "" || otlpEndpoint != ""
 
-func (pc pingdomCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- pingdomUpDesc
-	ch <- pingdomRateLimitRemainingRequestsDesc
-	ch <- pingdomOutageCheckPeriodDesc
-	ch <- pingdomCheckStatusDesc
-	ch <- pingdomCheckResponseTimeDesc
-	ch <- pingdomCheckAvailableErrorBudgetDesc
-	ch <- pingdomCheckErrorBudgetDesc
-	ch <- pingdomDownTimeDesc
-	ch <- pingdomUpTimeDesc
-	ch <- pingdomOutagesDesc
-}
+	registry.MustRegister(
+		collector,
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		prometheus.NewGoCollector(),
+	)
 
-func (pc pingdomCollector) Collect(ch chan<- prometheus.Metric) {
-	outageCheckPeriodDuration := time.Hour * time.Duration(24*outageCheckPeriod)
-	outageCheckPeriodSecs := float64(outageCheckPeriodDuration / time.Second)
+	gatherer := selector.Gatherer(registry)
 
-	checks, minReqLimit, err := pc.client.Checks.List(map[string]string{
-		"include_tags": "true",
-		"tags":         pc.client.Tags,
-	})
+	var pushTargets pushers
+	pushClient := &http.Client{Timeout: time.Minute}
+	if pushgatewayURL != "" {
+		pushTargets = append(pushTargets, newPushgatewayPusher(pushgatewayURL, pushJob, pushClient))
+	}
+	if remoteWriteURL != "" {
+		pushTargets = append(pushTargets, newRemoteWritePusher(remoteWriteURL, pushJob, pushClient))
+	}
+	if otlpEndpoint != "" {
+		p, err := newOTLPPusher(otlpEndpoint, otlpProtocol, otlpAccount, pushClient)
+		if err != nil {
+			logger.Error("Invalid OTLP exporter configuration, exiting", "err", err)
+			os.Exit(1)
+		}
+		pushTargets = append(pushTargets, p)
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		pingdomRateLimitRemainingRequestsDesc,
-		prometheus.GaugeValue,
-		minReqLimit,
-	)
+	// Honor the deprecated -port flag unless listen addresses were given
+	if isFlagSet("port") && !isFlagSet("web.listen-address") {
+		logger.Warn("The -port flag is deprecated, use -web.listen-address instead")
+		webListenAddresses = []string{fmt.Sprintf(":%d", port)}
+	}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting checks: %v", err)
-		ch <- prometheus.MustNewConstMetric(
-			pingdomUpDesc,
-			prometheus.GaugeValue,
-			float64(0),
-		)
-		return
+	server := NewServer(gatherer, metricsPath, collector.Ready, logger)
+	server.Handle("/explain", explainHandler(collector))
+
+	srv := &http.Server{
+		Handler:      server,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
 
-	ch <- prometheus.MustNewConstMetric(
-		pingdomUpDesc,
-		prometheus.GaugeValue,
-		float64(1),
-	)
+	webFlags := &web.FlagConfig{
+		WebListenAddresses: &webListenAddresses,
+		WebSystemdSocket:   &webSystemdSocket,
+		WebConfigFile:      &webConfigFile,
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		pingdomOutageCheckPeriodDesc,
-		prometheus.GaugeValue,
-		outageCheckPeriodSecs,
-	)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	var wg sync.WaitGroup
+	if tokenFile != "" {
+		go watchTokenFile(ctx, tokenFile, tokenFileRefresh, collector.client, logger)
+	}
 
-	for _, check := range checks {
+	if len(pushTargets) > 0 {
+		defer pushTargets.Close()
 
-		// Ignore this check based on the presence of the ignore label
-		if check.HasIgnoreTag() {
-			continue
+		logger.Info("Starting Pingdom Exporter in push mode", "version", VERSION, "once", pushOnce)
+		if pushOnce {
+			if err := gatherAndPush(ctx, gatherer, pushTargets); err != nil {
+				logger.Error("Cannot push metrics, exiting", "err", err)
+				os.Exit(1)
+			}
+			return
 		}
+		runPusher(ctx, gatherer, pushTargets, pushInterval, logger)
+		return
+	}
 
-		id := strconv.Itoa(check.ID)
-		tags := check.TagsString()
-		resolution := strconv.Itoa(check.Resolution)
+	logger.Info("Starting Pingdom Exporter", "version", VERSION)
+	listen := func() error {
+		return web.ListenAndServe(srv, webFlags, logger)
+	}
+	if err := serve(ctx, srv, listen, shutdownTimeout, logger); err != nil {
+		logger.Error("Cannot serve HTTP requests, exiting", "err", err)
+		os.Exit(1)
+	}
+}
 
-		var status float64
-		paused := "false"
-		if check.Status == "paused" {
-			paused = "true"
-		} else if check.Status == "up" {
-			status = 1
+// newCollectorFromFlags returns a collector configured via the flags and the
+// configuration file, along with the loaded configuration, so the exporter
+// and its subcommands select and label checks the same way. The metrics of
+// the Pingdom client are registered with registerer, if not nil.
+func newCollectorFromFlags(logger *slog.Logger, registerer prometheus.Registerer) (*pingdomCollector, *config, error) {
+	var err error
+	if tokenFile != "" {
+		if token, err = readTokenFile(tokenFile); err != nil {
+			return nil, nil, fmt.Errorf("cannot read Pingdom API token file: %w", err)
 		}
+	} else {
+		token = tokenFromEnv()
+	}
+	if token == "" && replayDir == "" {
+		return nil, nil, errors.New("Pingdom API token must be provided via -pingdom.token-file or the PINGDOM_API_TOKEN environment variable")
+	}
 
-		ch <- prometheus.MustNewConstMetric(
-			pingdomCheckStatusDesc,
-			prometheus.GaugeValue,
-			status,
-			id,
-			check.Name,
-			check.Hostname,
-			check.Status,
-			resolution,
-			paused,
-			tags,
-		)
-
-		ch <- prometheus.MustNewConstMetric(
-			pingdomCheckResponseTimeDesc,
-			prometheus.GaugeValue,
-			float64(check.LastResponseTime)/1000.0,
-			id,
-			check.Name,
-			check.Hostname,
-			check.Status,
-			resolution,
-			paused,
-			tags,
-		)
-
-		// Retrieve outages for check
-		var downCount, upTime, downTime float64
-
-		// Maximum allowed downtime, in seconds, according to the uptime SLO
-		uptimeErrorBudget := outageCheckPeriodSecs * (100.0 - check.UptimeSLOFromTags(defaultUptimeSLO)) / 100.0
-
-		// Retrieve the outage list within the desired period for this check, in background
-		wg.Add(1)
-
-		go func(check pingdom.CheckResponse) {
-			defer wg.Done()
-
-			// Retrieve the list of outages within the outage period for the given check
-			now := time.Now()
-			states, err := pc.client.OutageSummary.List(check.ID, map[string]string{
-				"from": strconv.FormatInt(now.Add(-outageCheckPeriodDuration).Unix(), 10),
-				"to":   strconv.FormatInt(now.Unix(), 10),
-			})
+	var httpClient *http.Client
+	switch {
+	case replayDir != "":
+		httpClient = &http.Client{Transport: fixtures.NewReplayer(replayDir)}
+	case recordDir != "":
+		httpClient = &http.Client{Transport: fixtures.NewRecorder(recordDir, nil)}
+	}
 
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error getting outages for check %d: %v", check.ID, err)
-				return
-			}
+	if forecastWindow <= 0 {
+		return nil, nil, errors.New("-forecast.window must be positive")
+	}
 
-			for _, state := range states {
-				switch state.Status {
-				case "down":
-					downCount = downCount + 1
-					downTime = downTime + float64(state.ToTime-state.FromTime)
-				case "up":
-					upTime = upTime + float64(state.ToTime-state.FromTime)
+	cfg := &config{}
+	if configFile != "" {
+		if cfg, err = loadConfig(configFile); err != nil {
+			return nil, nil, fmt.Errorf("cannot load configuration file %s: %w", configFile, err)
+		}
+	}
 
-				}
+	filter, err := newCheckFilter(
+		append(cfg.Checks.Include, includeChecks...),
+		append(cfg.Checks.Exclude, excludeChecks...),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tagLabelRules := cfg.Tags.Labels
+	for _, values := range []struct {
+		rules []string
+		regex bool
+	}{{tagLabelPrefixes, false}, {tagLabelRegexes, true}} {
+		for _, value := range values.rules {
+			rule, err := parseTagLabelRule(value, values.regex)
+			if err != nil {
+				return nil, nil, err
 			}
+			tagLabelRules = append(tagLabelRules, rule)
+		}
+	}
 
-			ch <- prometheus.MustNewConstMetric(
-				pingdomOutagesDesc,
-				prometheus.GaugeValue,
-				downCount,
-				id,
-				check.Name,
-				check.Hostname,
-				tags,
-			)
-
-			ch <- prometheus.MustNewConstMetric(
-				pingdomUpTimeDesc,
-				prometheus.GaugeValue,
-				upTime,
-				id,
-				check.Name,
-				check.Hostname,
-				tags,
-			)
-
-			ch <- prometheus.MustNewConstMetric(
-				pingdomDownTimeDesc,
-				prometheus.GaugeValue,
-				downTime,
-				id,
-				check.Name,
-				check.Hostname,
-				tags,
-			)
-
-			ch <- prometheus.MustNewConstMetric(
-				pingdomCheckErrorBudgetDesc,
-				prometheus.GaugeValue,
-				uptimeErrorBudget,
-				id,
-				check.Name,
-				check.Hostname,
-				tags,
-			)
-
-			ch <- prometheus.MustNewConstMetric(
-				pingdomCheckAvailableErrorBudgetDesc,
-				prometheus.GaugeValue,
-				uptimeErrorBudget-downTime,
-				id,
-				check.Name,
-				check.Hostname,
-				tags,
-			)
-		}(check)
+	if cfg.Tags.RawLabel != nil && !isFlagSet("tags.raw-label") {
+		rawTagsLabel = *cfg.Tags.RawLabel
 	}
 
-	wg.Wait()
-}
+	tagLabels, err := newTagLabeler(tagLabelRules, rawTagsLabel, checkMetricLabels)
+	if err != nil {
+		return nil, nil, err
+	}
 
-func main() {
-	var client *pingdom.Client
-	flag.Parse()
+	if !isFlagSet("metrics.stable-labels") {
+		stableLabels = cfg.Metrics.StableLabels
+	}
 
-	token = os.Getenv("PINGDOM_API_TOKEN")
-	if token == "" {
-		fmt.Fprintln(os.Stderr, "Pingdom API token must be provided via the PINGDOM_API_TOKEN environment variable, exiting")
-		os.Exit(1)
+	services, err := newServices(cfg.Services, defaultUptimeSLO)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	client, err := pingdom.NewClientWithConfig(pingdom.ClientConfig{
-		Token: token,
-		Tags:  tags,
+		Token:      token,
+		Tags:       tags,
+		BaseURL:    baseURL,
+		HTTPClient: httpClient,
+		Logger:     logger,
+		Registerer: registerer,
 	})
-
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Cannot create Pingdom client, exiting")
-		os.Exit(1)
+		return nil, nil, fmt.Errorf("cannot create Pingdom client: %w", err)
 	}
 
-	registry := prometheus.NewPedanticRegistry()
-	collector := pingdomCollector{
-		client: client,
+	collector := newPingdomCollector(client, time.Hour*time.Duration(24*outageCheckPeriod), defaultUptimeSLO)
+	collector.logger = logger
+	collector.minUptimeSLO = minUptimeSLO
+	collector.forecastWindow = forecastWindow
+	collector.staleOutageMaxAge = staleOutageMaxAge
+	collector.filter = filter
+	collector.tagLabels = tagLabels
+	collector.services = services
+	collector.stableLabels = stableLabels
+	collector.collectResults = collectResults
+	collector.collectEvents = collectEvents
+	collector.resultURLTemplate = resultURL
+
+	return collector, cfg, nil
+}
+
+// newSeriesSelectorFromFlags returns the series selector configured via the
+// flags and the configuration file.
+func newSeriesSelectorFromFlags(cfg *config) (*seriesSelector, error) {
+	if cfg.Metrics.MaxSeries != 0 && !isFlagSet("metrics.max-series") {
+		maxSeries = cfg.Metrics.MaxSeries
 	}
 
-	registry.MustRegister(
-		collector,
-		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
-		prometheus.NewGoCollector(),
+	return newSeriesSelector(
+		append(cfg.Metrics.Include, includeMetrics...),
+		append(cfg.Metrics.Exclude, excludeMetrics...),
+		append(cfg.Labels.Include, includeLabels...),
+		append(cfg.Labels.Exclude, excludeLabels...),
+		maxSeries,
 	)
-
-	http.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-             <head><title>Pingdom Exporter</title></head>
-             <body>
-             <h1>Pingdom Exporter</h1>
-             <p><a href='` + metricsPath + `'>Metrics</a></p>
-             </body>
-             </html>`))
-	})
-
-	fmt.Fprintf(os.Stdout, "Pingdom Exporter %v listening on http://0.0.0.0:%v\n", VERSION, port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
 }
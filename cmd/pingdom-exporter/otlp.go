@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// OTLP protocols, named as in the OTEL_EXPORTER_OTLP_PROTOCOL environment
+// variable of the OpenTelemetry SDKs.
+const (
+	otlpProtocolHTTP = "http/protobuf"
+	otlpProtocolGRPC = "grpc"
+)
+
+// otlpScopeName is the name of the instrumentation scope of the metrics.
+const otlpScopeName = "github.com/jusbrasil/pingdom-exporter"
+
+// otlpExportTimeout is the maximum duration of an export, so a stalled
+// collector doesn't block the following pushes.
+const otlpExportTimeout = time.Minute
+
+// otlpPusher sends the metrics to an OpenTelemetry
+// collector via OTLP, converting them as the Prometheus receiver of the
+// collector would, i.e. counters to cumulative sums starting at their created
+// timestamp. The metrics keep their Prometheus names and labels.
+type otlpPusher struct {
+	resource *resourcepb.Resource
+	export   func(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error)
+	timeout  time.Duration
+
+	// conn is the connection to the collector with the grpc protocol.
+	conn *grpc.ClientConn
+
+	// now returns the current time, overridden by tests.
+	now func() time.Time
+}
+
+// newOTLPPusher returns a pusher that exports the metrics to the given OTLP
+// endpoint, i.e. http://localhost:4318/v1/metrics for the
+// http/protobuf protocol or http://localhost:4317 for grpc, in which case the
+// https scheme enables TLS. The resource of the metrics has the service name
+// and version of the exporter, along with the Pingdom account, if given.
+func newOTLPPusher(endpoint, protocol, account string, client *http.Client) (*otlpPusher, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q, expected an http or https URL", endpoint)
+	}
+
+	p := &otlpPusher{
+		resource: otlpResource(account),
+		timeout:  otlpExportTimeout,
+		now:      time.Now,
+	}
+
+	switch protocol {
+	case otlpProtocolHTTP:
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/metrics"
+		}
+		p.export = otlpHTTPExporter(u.String(), client)
+	case otlpProtocolGRPC:
+		creds := insecure.NewCredentials()
+		if u.Scheme == "https" {
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+		conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("cannot create OTLP gRPC client: %w", err)
+		}
+		p.conn = conn
+		metricsClient := colmetricpb.NewMetricsServiceClient(conn)
+		p.export = func(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+			return metricsClient.Export(ctx, req)
+		}
+	default:
+		return nil, fmt.Errorf("invalid OTLP protocol %q, expected %s or %s", protocol, otlpProtocolHTTP, otlpProtocolGRPC)
+	}
+
+	return p, nil
+}
+
+// otlpHTTPExporter returns a function exporting metrics to the given URL via
+// the OTLP/HTTP protocol, with binary protobuf encoding.
+func otlpHTTPExporter(url string, client *http.Client) func(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	return func(ctx context.Context, exportReq *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+		body, err := proto.Marshal(exportReq)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("User-Agent", "pingdom-exporter/"+VERSION)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("unexpected status code %d while pushing to %s: %s", resp.StatusCode, url, strings.TrimSpace(string(respBody)))
+		}
+
+		exportResp := &colmetricpb.ExportMetricsServiceResponse{}
+		if err := proto.Unmarshal(respBody, exportResp); err != nil {
+			return nil, fmt.Errorf("invalid OTLP response from %s: %w", url, err)
+		}
+		return exportResp, nil
+	}
+}
+
+// otlpResource returns the resource of the exported metrics.
+func otlpResource(account string) *resourcepb.Resource {
+	r := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		otlpAttribute("service.name", "pingdom-exporter"),
+		otlpAttribute("service.version", VERSION),
+	}}
+	if account != "" {
+		r.Attributes = append(r.Attributes, otlpAttribute("pingdom.account", account))
+	}
+	return r
+}
+
+func otlpAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func (p *otlpPusher) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	resp, err := p.export(ctx, &colmetricpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricpb.ResourceMetrics{{
+			Resource: p.resource,
+			ScopeMetrics: []*metricpb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: otlpScopeName, Version: VERSION},
+				Metrics: otlpMetrics(families, p.now()),
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if partial := resp.GetPartialSuccess(); partial.GetRejectedDataPoints() > 0 {
+		return fmt.Errorf("%d data points rejected: %s", partial.GetRejectedDataPoints(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+// Close closes the connection to the collector, if any.
+func (p *otlpPusher) Close() error {
+	if p.conn == nil {
+		return nil
+	}
+	return p.conn.Close()
+}
+
+// otlpMetrics converts the metric families to OTLP metrics. Samples without a
+// timestamp get the given one.
+func otlpMetrics(families []*dto.MetricFamily, now time.Time) []*metricpb.Metric {
+	metrics := make([]*metricpb.Metric, 0, len(families))
+
+	for _, family := range families {
+		metric := &metricpb.Metric{
+			Name:        family.GetName(),
+			Description: family.GetHelp(),
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := &metricpb.Sum{
+				AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+			}
+			for _, m := range family.Metric {
+				sum.DataPoints = append(sum.DataPoints, &metricpb.NumberDataPoint{
+					Attributes:        otlpAttributes(m.Label),
+					StartTimeUnixNano: otlpStartTime(m.GetCounter().GetCreatedTimestamp()),
+					TimeUnixNano:      otlpTime(m, now),
+					Value:             &metricpb.NumberDataPoint_AsDouble{AsDouble: m.GetCounter().GetValue()},
+				})
+			}
+			metric.Data = &metricpb.Metric_Sum{Sum: sum}
+
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			gauge := &metricpb.Gauge{}
+			for _, m := range family.Metric {
+				value := m.GetGauge().GetValue()
+				if family.GetType() == dto.MetricType_UNTYPED {
+					value = m.GetUntyped().GetValue()
+				}
+				gauge.DataPoints = append(gauge.DataPoints, &metricpb.NumberDataPoint{
+					Attributes:   otlpAttributes(m.Label),
+					TimeUnixNano: otlpTime(m, now),
+					Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: value},
+				})
+			}
+			metric.Data = &metricpb.Metric_Gauge{Gauge: gauge}
+
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			histogram := &metricpb.Histogram{
+				AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			}
+			for _, m := range family.Metric {
+				histogram.DataPoints = append(histogram.DataPoints, otlpHistogramDataPoint(m, now))
+			}
+			metric.Data = &metricpb.Metric_Histogram{Histogram: histogram}
+
+		case dto.MetricType_SUMMARY:
+			summary := &metricpb.Summary{}
+			for _, m := range family.Metric {
+				s := m.GetSummary()
+				point := &metricpb.SummaryDataPoint{
+					Attributes:        otlpAttributes(m.Label),
+					StartTimeUnixNano: otlpStartTime(s.GetCreatedTimestamp()),
+					TimeUnixNano:      otlpTime(m, now),
+					Count:             s.GetSampleCount(),
+					Sum:               s.GetSampleSum(),
+				}
+				for _, q := range s.GetQuantile() {
+					point.QuantileValues = append(point.QuantileValues, &metricpb.SummaryDataPoint_ValueAtQuantile{
+						Quantile: q.GetQuantile(),
+						Value:    q.GetValue(),
+					})
+				}
+				summary.DataPoints = append(summary.DataPoints, point)
+			}
+			metric.Data = &metricpb.Metric_Summary{Summary: summary}
+
+		default:
+			continue
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+// otlpHistogramDataPoint converts a Prometheus histogram, whose buckets are
+// cumulative, to an OTLP one, whose buckets aren't.
+func otlpHistogramDataPoint(m *dto.Metric, now time.Time) *metricpb.HistogramDataPoint {
+	h := m.GetHistogram()
+	sum := h.GetSampleSum()
+	point := &metricpb.HistogramDataPoint{
+		Attributes:        otlpAttributes(m.Label),
+		StartTimeUnixNano: otlpStartTime(h.GetCreatedTimestamp()),
+		TimeUnixNano:      otlpTime(m, now),
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+	}
+
+	var previous uint64
+	for _, b := range h.GetBucket() {
+		// The exemplar of the +Inf bucket, i.e. of the slowest results, is
+		// kept, while the bucket itself is implicit in OTLP
+		if e := b.GetExemplar(); e != nil {
+			point.Exemplars = append(point.Exemplars, &metricpb.Exemplar{
+				FilteredAttributes: otlpAttributes(e.Label),
+				TimeUnixNano:       uint64(e.GetTimestamp().AsTime().UnixNano()),
+				Value:              &metricpb.Exemplar_AsDouble{AsDouble: e.GetValue()},
+			})
+		}
+
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, b.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, b.GetCumulativeCount()-previous)
+		previous = b.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-previous)
+
+	return point
+}
+
+// otlpAttributes converts Prometheus labels to OTLP attributes.
+func otlpAttributes(labels []*dto.LabelPair) []*commonpb.KeyValue {
+	attributes := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attributes = append(attributes, otlpAttribute(l.GetName(), l.GetValue()))
+	}
+	return attributes
+}
+
+// otlpTime returns the timestamp of the sample, or now if it has none.
+func otlpTime(m *dto.Metric, now time.Time) uint64 {
+	if m.TimestampMs != nil {
+		return uint64(time.UnixMilli(m.GetTimestampMs()).UnixNano())
+	}
+	return uint64(now.UnixNano())
+}
+
+// otlpStartTime returns the start time of cumulative metrics, which is unknown
+// without a created timestamp.
+func otlpStartTime(created *timestamppb.Timestamp) uint64 {
+	if created == nil {
+		return 0
+	}
+	return uint64(created.AsTime().UnixNano())
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpCollector is a stand-in for the OTLP receiver of an OpenTelemetry
+// collector, recording the exported metrics.
+type otlpCollector struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	requests []*colmetricpb.ExportMetricsServiceRequest
+	response *colmetricpb.ExportMetricsServiceResponse
+
+	// stalled makes exports hang until canceled.
+	stalled bool
+}
+
+func (c *otlpCollector) Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	if c.stalled {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	c.requests = append(c.requests, req)
+	if c.response != nil {
+		return c.response, nil
+	}
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	req := &colmetricpb.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, _ := c.Export(r.Context(), req)
+	b, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(b)
+}
+
+// metric returns the exported metric with the given name.
+func (c *otlpCollector) metric(t *testing.T, name string) *metricpb.Metric {
+	require.Len(t, c.requests, 1)
+	for _, m := range c.requests[0].ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		if m.Name == name {
+			return m
+		}
+	}
+	require.Failf(t, "metric not found", "%s", name)
+	return nil
+}
+
+func otlpAttributeMap(attributes []*commonpb.KeyValue) map[string]string {
+	m := map[string]string{}
+	for _, a := range attributes {
+		m[a.Key] = a.Value.GetStringValue()
+	}
+	return m
+}
+
+func assertOTLPMetrics(t *testing.T, c *otlpCollector) {
+	resource := c.requests[0].ResourceMetrics[0].Resource
+	assert.Equal(t, map[string]string{
+		"service.name":    "pingdom-exporter",
+		"service.version": VERSION,
+		"pingdom.account": "acme",
+	}, otlpAttributeMap(resource.Attributes))
+
+	// The status is timestamped with the time of the last test of the check
+	status := c.metric(t, "pingdom_uptime_status").GetGauge().DataPoints[0]
+	assert.Equal(t, 1.0, status.GetAsDouble())
+	assert.Equal(t, uint64(testNow.Add(-time.Minute).UnixNano()), status.TimeUnixNano)
+	assert.Equal(t, map[string]string{
+		"id":         "1",
+		"name":       "api",
+		"hostname":   "api.example.com",
+		"status":     "up",
+		"resolution": "1",
+		"paused":     "false",
+		"tags":       "",
+	}, otlpAttributeMap(status.Attributes))
+
+	// Counters are cumulative sums starting at their created timestamp
+	outages := c.metric(t, "pingdom_outages_total").GetSum()
+	assert.True(t, outages.IsMonotonic)
+	assert.Equal(t, metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, outages.AggregationTemporality)
+	assert.Equal(t, 1.0, outages.DataPoints[0].GetAsDouble())
+	assert.Equal(t, uint64(testNow.Add(-7*24*time.Hour).UnixNano()), outages.DataPoints[0].StartTimeUnixNano)
+	assert.Equal(t, uint64(testNow.UnixNano()), outages.DataPoints[0].TimeUnixNano)
+
+	budget := c.metric(t, "pingdom_uptime_slo_error_budget_available_seconds").GetGauge().DataPoints[0]
+	assert.Equal(t, 6048.0-600.0, budget.GetAsDouble())
+}
+
+func TestOTLPPusherHTTP(t *testing.T) {
+	c := &otlpCollector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	p, err := newOTLPPusher(server.URL, otlpProtocolHTTP, "acme", server.Client())
+	require.NoError(t, err)
+	p.now = func() time.Time { return testNow }
+
+	require.NoError(t, p.Push(context.Background(), testPushFamilies(t, true)))
+	assertOTLPMetrics(t, c)
+}
+
+func TestOTLPPusherGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	c := &otlpCollector{}
+	server := grpc.NewServer()
+	colmetricpb.RegisterMetricsServiceServer(server, c)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	p, err := newOTLPPusher("http://"+listener.Addr().String(), otlpProtocolGRPC, "acme", nil)
+	require.NoError(t, err)
+	p.now = func() time.Time { return testNow }
+
+	require.NoError(t, p.Push(context.Background(), testPushFamilies(t, true)))
+	assertOTLPMetrics(t, c)
+
+	// Rejected data points are reported as errors
+	c.requests = nil
+	c.response = &colmetricpb.ExportMetricsServiceResponse{PartialSuccess: &colmetricpb.ExportMetricsPartialSuccess{
+		RejectedDataPoints: 2,
+		ErrorMessage:       "out of order",
+	}}
+	assert.EqualError(t, p.Push(context.Background(), testPushFamilies(t, true)), "2 data points rejected: out of order")
+
+	// Exports to a stalled collector time out
+	c.stalled = true
+	p.timeout = 50 * time.Millisecond
+	err = p.Push(context.Background(), testPushFamilies(t, true))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	// The connection is closed on shutdown
+	require.NoError(t, pushers{p}.Close())
+	assert.Error(t, p.Push(context.Background(), testPushFamilies(t, true)))
+}
+
+func TestNewOTLPPusherInvalid(t *testing.T) {
+	_, err := newOTLPPusher("localhost:4317", otlpProtocolGRPC, "", nil)
+	assert.EqualError(t, err, `invalid OTLP endpoint "localhost:4317", expected an http or https URL`)
+
+	_, err = newOTLPPusher("http://localhost:4318", "http/json", "", nil)
+	assert.EqualError(t, err, `invalid OTLP protocol "http/json", expected http/protobuf or grpc`)
+}
+
+func TestOTLPMetricsHistogram(t *testing.T) {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_seconds",
+		Help:    "Test histogram",
+		Buckets: []float64{.5, 1},
+	})
+	h.Observe(.2)
+	h.(prometheus.ExemplarObserver).ObserveWithExemplar(.3, prometheus.Labels{"probe_id": "63"})
+	h.(prometheus.ExemplarObserver).ObserveWithExemplar(2, prometheus.Labels{"probe_id": "64"})
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(h)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	metrics := otlpMetrics(families, testNow)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "Test histogram", metrics[0].Description)
+
+	point := metrics[0].GetHistogram().DataPoints[0]
+	assert.Equal(t, []float64{.5, 1}, point.ExplicitBounds)
+	assert.Equal(t, []uint64{2, 0, 1}, point.BucketCounts)
+	assert.Equal(t, uint64(3), point.Count)
+	assert.InDelta(t, 2.5, point.GetSum(), 1e-9)
+	assert.NotZero(t, point.StartTimeUnixNano)
+
+	// The exemplar above the largest bound is exported as well
+	require.Len(t, point.Exemplars, 2)
+	assert.Equal(t, .3, point.Exemplars[0].GetAsDouble())
+	assert.Equal(t, 2.0, point.Exemplars[1].GetAsDouble())
+	assert.Equal(t, map[string]string{"probe_id": "64"}, otlpAttributeMap(point.Exemplars[1].FilteredAttributes))
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// pusher pushes a snapshot of the metrics gathered by the exporter.
+type pusher interface {
+	Push(ctx context.Context, families []*dto.MetricFamily) error
+}
+
+// pushers pushes the same snapshot with every pusher, i.e. to both a
+// Pushgateway and a remote write endpoint.
+type pushers []pusher
+
+func (ps pushers) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	var errs []error
+	for _, p := range ps {
+		errs = append(errs, p.Push(ctx, families))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the pushers holding a connection, i.e. to an OTLP collector
+// via gRPC.
+func (ps pushers) Close() error {
+	var errs []error
+	for _, p := range ps {
+		if c, ok := p.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gatherAndPush gathers a snapshot of the metrics once, so the Pingdom API is
+// queried once per push whatever the number of targets, and pushes it.
+func gatherAndPush(ctx context.Context, g prometheus.Gatherer, p pusher) error {
+	families, err := g.Gather()
+	if err != nil {
+		return err
+	}
+	return p.Push(ctx, families)
+}
+
+// newPushgatewayPusher returns a pusher that replaces the metrics of the
+// given job in a Pushgateway with the pushed ones. The Pushgateway rejects
+// timestamped samples, so the timestamps of the metrics are dropped.
+func newPushgatewayPusher(url, job string, client *http.Client) pusher {
+	return pushgatewayPusher{url: url, job: job, client: client}
+}
+
+type pushgatewayPusher struct {
+	url    string
+	job    string
+	client *http.Client
+}
+
+func (p pushgatewayPusher) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	untimestamped := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		// The snapshot is shared with the other pushers
+		clones := make([]*dto.MetricFamily, len(families))
+		for i, family := range families {
+			clones[i] = proto.Clone(family).(*dto.MetricFamily)
+			for _, m := range clones[i].Metric {
+				m.TimestampMs = nil
+			}
+		}
+		return clones, nil
+	})
+	return push.New(p.url, p.job).Gatherer(untimestamped).Client(p.client).PushContext(ctx)
+}
+
+// remoteWritePusher sends the metrics to a Prometheus remote write endpoint,
+// using the version 1.0 of the protocol. Samples without a timestamp are sent
+// with the time of the push.
+type remoteWritePusher struct {
+	url    string
+	job    string
+	client *http.Client
+
+	// now returns the current time, overridden by tests.
+	now func() time.Time
+}
+
+// newRemoteWritePusher returns a pusher that sends the metrics to the given
+// remote write URL, with the job label set to job.
+func newRemoteWritePusher(url, job string, client *http.Client) *remoteWritePusher {
+	return &remoteWritePusher{
+		url:    url,
+		job:    job,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (p *remoteWritePusher) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	series := remoteWriteSeries(families, p.job, p.now())
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "pingdom-exporter/"+VERSION)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status code %d while pushing to %s: %s", resp.StatusCode, p.url, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// remoteWriteLabel is a label of a remote write time series.
+type remoteWriteLabel struct {
+	name, value string
+}
+
+// remoteWriteSample is a remote write time series with a single sample.
+type remoteWriteSample struct {
+	labels    []remoteWriteLabel
+	value     float64
+	timestamp int64
+}
+
+// remoteWriteSeries converts the metric families to remote write time series,
+// as Prometheus would store them when scraping the exporter, i.e. one series
+// per bucket plus the sum and count for histograms. The job label is added to
+// series without one, and samples without a timestamp get the given one.
+func remoteWriteSeries(families []*dto.MetricFamily, job string, now time.Time) []remoteWriteSample {
+	var series []remoteWriteSample
+
+	for _, family := range families {
+		name := family.GetName()
+
+		for _, m := range family.Metric {
+			timestamp := now.UnixMilli()
+			if m.TimestampMs != nil {
+				timestamp = m.GetTimestampMs()
+			}
+
+			add := func(suffix string, value float64, extra ...remoteWriteLabel) {
+				labels := []remoteWriteLabel{{"__name__", name + suffix}}
+				hasJob := false
+				for _, l := range m.Label {
+					labels = append(labels, remoteWriteLabel{l.GetName(), l.GetValue()})
+					hasJob = hasJob || l.GetName() == "job"
+				}
+				if !hasJob && job != "" {
+					labels = append(labels, remoteWriteLabel{"job", job})
+				}
+				labels = append(labels, extra...)
+
+				// Remote write requires the labels to be sorted by name
+				slices.SortFunc(labels, func(a, b remoteWriteLabel) int {
+					return strings.Compare(a.name, b.name)
+				})
+
+				series = append(series, remoteWriteSample{labels, value, timestamp})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add("", q.GetValue(), remoteWriteLabel{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					if !math.IsInf(b.GetUpperBound(), 1) {
+						add("_bucket", float64(b.GetCumulativeCount()), remoteWriteLabel{"le", formatFloat(b.GetUpperBound())})
+					}
+				}
+				add("_bucket", float64(h.GetSampleCount()), remoteWriteLabel{"le", "+Inf"})
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+
+	return series
+}
+
+// formatFloat formats the value of the le and quantile labels as Prometheus
+// does.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes the time series as a remote write WriteRequest
+// protobuf message:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []remoteWriteSample) []byte {
+	var req []byte
+
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.value)
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+
+	return req
+}
+
+// runPusher gathers and pushes the metrics right away and then every interval
+// until the context is canceled. Failed pushes are logged and retried at the
+// next interval.
+func runPusher(ctx context.Context, g prometheus.Gatherer, p pusher, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := gatherAndPush(ctx, g, p); err != nil && ctx.Err() == nil {
+			logger.Error("Error pushing metrics", "err", err)
+		} else if err == nil {
+			logger.Debug("Pushed metrics")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
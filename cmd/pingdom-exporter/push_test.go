@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// testPushCollector returns a registry with a collector exporting a single
+// check, with the timestamps of its last test when lastTestTimestamps is set.
+func testPushCollector(t *testing.T, lastTestTimestamps bool) prometheus.Gatherer {
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks:  []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{1: testOutages(10 * time.Minute)},
+	})
+	t.Cleanup(server.Close)
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	collector.now = func() time.Time { return testNow }
+	collector.lastTestTimestamps = lastTestTimestamps
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+	return registry
+}
+
+// testPushFamilies returns a snapshot of the metrics of testPushCollector.
+func testPushFamilies(t *testing.T, lastTestTimestamps bool) []*dto.MetricFamily {
+	families, err := testPushCollector(t, lastTestTimestamps).Gather()
+	require.NoError(t, err)
+	return families
+}
+
+// decodeWriteRequest decodes a remote write WriteRequest into samples.
+func decodeWriteRequest(t *testing.T, b []byte) []remoteWriteSample {
+	// field consumes the fields of a message, calling fn with each one
+	field := func(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) int) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			n = fn(num, typ, b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+		}
+	}
+
+	var series []remoteWriteSample
+	field(b, func(_ protowire.Number, _ protowire.Type, b []byte) int {
+		ts, n := protowire.ConsumeBytes(b)
+		var s remoteWriteSample
+		field(ts, func(num protowire.Number, _ protowire.Type, b []byte) int {
+			msg, n := protowire.ConsumeBytes(b)
+			switch num {
+			case 1:
+				var l remoteWriteLabel
+				field(msg, func(num protowire.Number, _ protowire.Type, b []byte) int {
+					v, n := protowire.ConsumeString(b)
+					if num == 1 {
+						l.name = v
+					} else {
+						l.value = v
+					}
+					return n
+				})
+				s.labels = append(s.labels, l)
+			case 2:
+				field(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+					if num == 1 {
+						v, n := protowire.ConsumeFixed64(b)
+						s.value = math.Float64frombits(v)
+						return n
+					}
+					v, n := protowire.ConsumeVarint(b)
+					s.timestamp = int64(v)
+					return n
+				})
+			}
+			return n
+		})
+		series = append(series, s)
+		return n
+	})
+	return series
+}
+
+func TestRemoteWritePusher(t *testing.T) {
+	var series []remoteWriteSample
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		decoded, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		series = decodeWriteRequest(t, decoded)
+	}))
+	defer server.Close()
+
+	p := newRemoteWritePusher(server.URL, "pingdom", server.Client())
+	p.now = func() time.Time { return testNow }
+	require.NoError(t, p.Push(context.Background(), testPushFamilies(t, true)))
+
+	find := func(name string) remoteWriteSample {
+		for _, s := range series {
+			if s.labels[0].value == name {
+				return s
+			}
+		}
+		require.Failf(t, "series not found", "%s", name)
+		return remoteWriteSample{}
+	}
+
+	// The status is timestamped with the time of the last test of the check
+	status := find("pingdom_uptime_status")
+	assert.Equal(t, []remoteWriteLabel{
+		{"__name__", "pingdom_uptime_status"},
+		{"hostname", "api.example.com"},
+		{"id", "1"},
+		{"job", "pingdom"},
+		{"name", "api"},
+		{"paused", "false"},
+		{"resolution", "1"},
+		{"status", "up"},
+		{"tags", ""},
+	}, status.labels)
+	assert.Equal(t, 1.0, status.value)
+	assert.Equal(t, testNow.Add(-time.Minute).UnixMilli(), status.timestamp)
+
+	// Other samples are timestamped with the time of the push
+	downTime := find("pingdom_down_seconds")
+	assert.Equal(t, 600.0, downTime.value)
+	assert.Equal(t, testNow.UnixMilli(), downTime.timestamp)
+}
+
+func TestRemoteWritePusherError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	p := newRemoteWritePusher(server.URL, "pingdom", server.Client())
+	assert.EqualError(t, p.Push(context.Background(), testPushFamilies(t, true)), "unexpected status code 400 while pushing to "+server.URL+": out of order sample")
+}
+
+func TestRemoteWriteSeriesHistogram(t *testing.T) {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_seconds",
+		Help:    "Test histogram",
+		Buckets: []float64{.5, 1},
+	})
+	h.Observe(.2)
+	h.Observe(2)
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(h)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var got []string
+	for _, s := range remoteWriteSeries(families, "", testNow) {
+		labels := ""
+		for _, l := range s.labels[1:] {
+			labels += l.name + "=" + l.value
+		}
+		got = append(got, s.labels[0].value+"{"+labels+"} "+formatFloat(s.value))
+	}
+
+	assert.Equal(t, []string{
+		"test_seconds_bucket{le=0.5} 1",
+		"test_seconds_bucket{le=1} 1",
+		"test_seconds_bucket{le=+Inf} 2",
+		"test_seconds_sum{} 2.2",
+		"test_seconds_count{} 2",
+	}, got)
+}
+
+func TestPushgatewayPusher(t *testing.T) {
+	var families []*dto.MetricFamily
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/metrics/job/pingdom", r.URL.Path)
+
+		decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			family := &dto.MetricFamily{}
+			if err := decoder.Decode(family); err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				break
+			}
+			families = append(families, family)
+		}
+	}))
+	defer server.Close()
+
+	snapshot := testPushFamilies(t, true)
+	p := newPushgatewayPusher(server.URL, "pingdom", server.Client())
+	require.NoError(t, p.Push(context.Background(), snapshot))
+
+	// The Pushgateway rejects timestamped samples
+	require.NotEmpty(t, families)
+	for _, family := range families {
+		for _, m := range family.Metric {
+			assert.Nil(t, m.TimestampMs, family.GetName())
+		}
+	}
+
+	// The snapshot shared with the other pushers is left untouched
+	timestamped := 0
+	for _, family := range snapshot {
+		for _, m := range family.Metric {
+			if m.TimestampMs != nil {
+				timestamped++
+			}
+		}
+	}
+	assert.NotZero(t, timestamped)
+}
+
+func TestRunPusher(t *testing.T) {
+	gathers := 0
+	g := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		gathers++
+		return []*dto.MetricFamily{{Name: proto.String("test_total")}}, nil
+	})
+
+	// Every pusher pushes the same snapshot, gathered once per push
+	ctx, cancel := context.WithCancel(context.Background())
+	var pushed [][]*dto.MetricFamily
+	push := pusherFunc(func(_ context.Context, families []*dto.MetricFamily) error {
+		pushed = append(pushed, families)
+		if len(pushed) == 6 {
+			cancel()
+		}
+		return nil
+	})
+
+	runPusher(ctx, g, pushers{push, push}, time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Equal(t, 3, gathers)
+	require.Len(t, pushed, 6)
+	assert.Same(t, pushed[0][0], pushed[1][0])
+	assert.NotSame(t, pushed[1][0], pushed[2][0])
+}
+
+type pusherFunc func(ctx context.Context, families []*dto.MetricFamily) error
+
+func (f pusherFunc) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	return f(ctx, families)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+var (
+	reportFrom    string
+	reportTo      string
+	reportFormat  string
+	reportGroupBy string
+	reportOutput  string
+)
+
+var reportCommand = &command{
+	name: "report",
+	help: "write the availability, downtime, outages and error budget of the checks within a period of time against their uptime SLO, as CSV, JSON or Markdown",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&reportFrom, "from", "", "start of the period to report on, as a RFC 3339 timestamp, a date or a duration ago, i.e. 2024-01-01 or 30d (defaults to the outage check period before -to)")
+		fs.StringVar(&reportTo, "to", "", "end of the period to report on, in the same format as -from (defaults to now)")
+		fs.StringVar(&reportFormat, "format", "markdown", "output format of the report (one of: csv, json, markdown)")
+		fs.StringVar(&reportGroupBy, "group-by", "", "group the checks by tag or team; checks with several tags or teams are reported in each group (one of: tag, team)")
+		fs.StringVar(&reportOutput, "output", "-", "file to write the report to, or - for the standard output")
+	},
+	run: runReport,
+}
+
+// reportWriters write a report, by format.
+var reportWriters = map[string]func(w io.Writer, r *report) error{
+	"csv":      writeReportCSV,
+	"json":     writeReportJSON,
+	"markdown": writeReportMarkdown,
+}
+
+func runReport(ctx context.Context, c *commandContext) error {
+	write, ok := reportWriters[reportFormat]
+	if !ok {
+		return fmt.Errorf("invalid report format %q", reportFormat)
+	}
+	if reportGroupBy != "" && reportGroupBy != "tag" && reportGroupBy != "team" {
+		return fmt.Errorf("invalid report grouping %q", reportGroupBy)
+	}
+
+	from, to, err := parsePeriod(reportFrom, reportTo, c.collector.now(), c.collector.outageCheckPeriod)
+	if err != nil {
+		return err
+	}
+
+	checks, err := c.collector.exportedChecks()
+	if err != nil {
+		return fmt.Errorf("cannot retrieve checks: %w", err)
+	}
+
+	r, err := newReport(ctx, c.collector, checks, from, to, reportGroupBy)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(reportOutput, c.stdout, func(w io.Writer) error {
+		return write(w, r)
+	})
+}
+
+// report holds the availability of the checks within a period of time.
+type report struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Checks []reportCheckRow `json:"checks"`
+}
+
+// reportCheckRow is the availability of a check within the period of the
+// report. Availability, SLO and budget consumed are percentages.
+type reportCheckRow struct {
+	// Group is the tag or team of the check the row is grouped by, empty
+	// when not grouping or the check has none.
+	Group string `json:"group,omitempty"`
+
+	ID                  int     `json:"id"`
+	Name                string  `json:"name"`
+	Hostname            string  `json:"hostname"`
+	UptimeSLO           float64 `json:"uptime_slo"`
+	Availability        float64 `json:"availability"`
+	DowntimeSeconds     float64 `json:"downtime_seconds"`
+	Outages             int     `json:"outages"`
+	ErrorBudgetSeconds  float64 `json:"error_budget_seconds"`
+	ErrorBudgetConsumed float64 `json:"error_budget_consumed"`
+	Pass                bool    `json:"pass"`
+}
+
+// newReport retrieves the outage states of the checks within the period and
+// computes their availability, with a row per group of every check when
+// grouping them. Rows are sorted by group, checks without any group last,
+// then by check name.
+func newReport(ctx context.Context, pc *pingdomCollector, checks []pingdom.CheckResponse, from, to time.Time, groupBy string) (*report, error) {
+	r := &report{From: from.UTC(), To: to.UTC(), Checks: []reportCheckRow{}}
+
+	for _, check := range checks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		states, err := pc.client.OutageSummary.List(check.ID, map[string]string{
+			"from": strconv.FormatInt(from.Unix(), 10),
+			"to":   strconv.FormatInt(to.Unix(), 10),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot retrieve outages of check %d: %w", check.ID, err)
+		}
+
+		row := newReportCheckRow(check, states, from, to, pc.uptimeSLO(check).Value)
+		for _, group := range reportGroups(check, groupBy) {
+			row.Group = group
+			r.Checks = append(r.Checks, row)
+		}
+	}
+
+	slices.SortStableFunc(r.Checks, func(a, b reportCheckRow) int {
+		if (a.Group == "") != (b.Group == "") {
+			if a.Group == "" {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(
+			cmp.Compare(a.Group, b.Group),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	return r, nil
+}
+
+// newReportCheckRow computes the availability of the check within the period
+// from its outage states and uptime SLO. The availability only accounts for the time the
+// check was up or down, i.e. not before it was created. The error budget,
+// as the one exported, is the allowed downtime within the whole period.
+func newReportCheckRow(check pingdom.CheckResponse, states []pingdom.OutageSummaryResponseState, from, to time.Time, slo float64) reportCheckRow {
+	upTime, downTime := outageTimes(states, from, to)
+	budget := to.Sub(from).Seconds() * (100.0 - slo) / 100.0
+
+	availability := 100.0
+	if upTime+downTime > 0 {
+		availability = 100.0 * upTime / (upTime + downTime)
+	}
+
+	// With a 100% SLO, any downtime consumes the whole budget
+	var consumed float64
+	if budget > 0 {
+		consumed = 100.0 * downTime / budget
+	} else if downTime > 0 {
+		consumed = 100.0
+	}
+
+	var outages int
+	for _, state := range states {
+		if state.Status == "down" && state.ToTime > from.Unix() && state.FromTime < to.Unix() {
+			outages++
+		}
+	}
+
+	return reportCheckRow{
+		ID:                  check.ID,
+		Name:                check.Name,
+		Hostname:            check.Hostname,
+		UptimeSLO:           slo,
+		Availability:        availability,
+		DowntimeSeconds:     downTime,
+		Outages:             outages,
+		ErrorBudgetSeconds:  budget,
+		ErrorBudgetConsumed: consumed,
+		Pass:                downTime <= budget,
+	}
+}
+
+// reportGroups returns the groups of the check, which has a single empty
+// group when not grouping or it has no tags or teams. The uptime and latency
+// SLO tags are not groups, and the teams are the ones the team selector
+// matches, so checks selected by team are grouped by it.
+func reportGroups(check pingdom.CheckResponse, groupBy string) []string {
+	var groups []string
+	switch groupBy {
+	case "tag":
+		for _, tag := range check.Tags {
+			if !strings.HasPrefix(tag.Name, "uptime_slo_") && !strings.HasPrefix(tag.Name, "latency_slo_") {
+				groups = append(groups, tag.Name)
+			}
+		}
+	case "team":
+		groups = checkFields["team"](check)
+	}
+
+	if len(groups) == 0 {
+		return []string{""}
+	}
+	slices.Sort(groups)
+	return slices.Compact(groups)
+}
+
+func writeReportCSV(w io.Writer, r *report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"group", "id", "name", "hostname", "uptime_slo", "availability", "downtime_seconds",
+		"outages", "error_budget_seconds", "error_budget_consumed", "pass",
+	})
+
+	for _, row := range r.Checks {
+		cw.Write([]string{
+			row.Group,
+			strconv.Itoa(row.ID),
+			row.Name,
+			row.Hostname,
+			formatFloat(row.UptimeSLO),
+			formatFloat(row.Availability),
+			formatFloat(row.DowntimeSeconds),
+			strconv.Itoa(row.Outages),
+			formatFloat(row.ErrorBudgetSeconds),
+			formatFloat(row.ErrorBudgetConsumed),
+			strconv.FormatBool(row.Pass),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeReportJSON(w io.Writer, r *report) error {
+	return writeJSON(w, r)
+}
+
+// writeReportMarkdown writes the report as a table per group, with rounded
+// figures and durations, to be read by humans.
+func writeReportMarkdown(w io.Writer, r *report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Availability Report\n\nFrom %s to %s.\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+
+	grouped := slices.ContainsFunc(r.Checks, func(row reportCheckRow) bool {
+		return row.Group != ""
+	})
+
+	for i, row := range r.Checks {
+		if i == 0 || row.Group != r.Checks[i-1].Group {
+			b.WriteString("\n")
+			if grouped {
+				group := row.Group
+				if group == "" {
+					group = "Other"
+				}
+				fmt.Fprintf(&b, "## %s\n\n", markdownEscape(group))
+			}
+			b.WriteString("| Check | Hostname | SLO | Availability | Downtime | Outages | Budget Consumed | Result |\n")
+			b.WriteString("|-------|----------|----:|-------------:|---------:|--------:|----------------:|--------|\n")
+		}
+
+		result := "PASS"
+		if !row.Pass {
+			result = "FAIL"
+		}
+
+		fmt.Fprintf(&b, "| %s (%d) | %s | %s%% | %.3f%% | %s | %d | %.1f%% | %s |\n",
+			markdownEscape(row.Name),
+			row.ID,
+			markdownEscape(row.Hostname),
+			formatFloat(row.UptimeSLO),
+			row.Availability,
+			time.Duration(row.DowntimeSeconds)*time.Second,
+			row.Outages,
+			row.ErrorBudgetConsumed,
+			result,
+		)
+	}
+
+	if len(r.Checks) == 0 {
+		b.WriteString("\nNo checks.\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape escapes the characters breaking a Markdown table cell.
+func markdownEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`).Replace(s)
+}
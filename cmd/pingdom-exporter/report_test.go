@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	web := testCheck(2, "web", "up", "frontend", "payments")
+	web.Teams = []pingdom.CheckTeamResponse{{ID: 1, Name: "Web | Mobile"}}
+
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			testCheck(1, "api", "up", "payments", "uptime_slo_999", "latency_slo_p95_500ms"),
+			web,
+			testCheck(3, "db", "up"),
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{
+			1: testOutages(10 * time.Minute),
+			2: testOutages(2 * time.Hour),
+		},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	collector := newPingdomCollector(client, 7*24*time.Hour, 99)
+	checks, err := collector.exportedChecks()
+	require.NoError(t, err)
+
+	newTestReport := func(groupBy string) *report {
+		r, err := newReport(context.Background(), collector, checks, testNow.Add(-7*24*time.Hour), testNow, groupBy)
+		require.NoError(t, err)
+		return r
+	}
+
+	for _, format := range []string{"csv", "json", "markdown"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, reportWriters[format](&buf, newTestReport("tag")))
+
+			path := filepath.Join("testdata", "report."+format)
+			if *update {
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+
+	// Checks without teams are grouped together, last
+	r := newTestReport("team")
+	require.Len(t, r.Checks, 3)
+	assert.Equal(t, "Web | Mobile", r.Checks[0].Group)
+	assert.Equal(t, []string{"", ""}, []string{r.Checks[1].Group, r.Checks[2].Group})
+	assert.Equal(t, []string{"api", "db"}, []string{r.Checks[1].Name, r.Checks[2].Name})
+
+	r = newTestReport("")
+	require.Len(t, r.Checks, 3)
+	assert.Equal(t, "web", r.Checks[2].Name)
+	assert.Equal(t, 1, r.Checks[2].Outages)
+	assert.Equal(t, 7200.0, r.Checks[2].DowntimeSeconds)
+	assert.False(t, r.Checks[2].Pass)
+}
+
+func TestReportGroups(t *testing.T) {
+	check := testCheck(1, "api", "up", "payments", "team:checkout", "uptime_slo_999")
+	check.Teams = []pingdom.CheckTeamResponse{{ID: 1, Name: "web"}}
+
+	assert.Equal(t, []string{""}, reportGroups(check, ""))
+	assert.Equal(t, []string{"payments", "team:checkout"}, reportGroups(check, "tag"))
+
+	// Teams are the ones the team selector matches, tags included
+	assert.Equal(t, []string{"checkout", "web"}, reportGroups(check, "team"))
+	selector, err := parseCheckSelector("team=checkout")
+	require.NoError(t, err)
+	assert.True(t, selector.Match(check))
+
+	assert.Equal(t, []string{""}, reportGroups(testCheck(2, "db", "up", "team_"), "team"))
+}
+
+func TestNewReportCheckRow(t *testing.T) {
+	from := testNow.Add(-7 * 24 * time.Hour)
+
+	// The availability only accounts for the time the check existed
+	row := newReportCheckRow(testCheck(1, "api", "up"), []pingdom.OutageSummaryResponseState{
+		{Status: "up", FromTime: testNow.Add(-time.Hour).Unix(), ToTime: testNow.Add(-30 * time.Minute).Unix()},
+		{Status: "down", FromTime: testNow.Add(-30 * time.Minute).Unix(), ToTime: testNow.Unix()},
+	}, from, testNow, 100)
+
+	assert.Equal(t, 50.0, row.Availability)
+	assert.Equal(t, 1, row.Outages)
+	assert.Equal(t, 0.0, row.ErrorBudgetSeconds)
+	assert.Equal(t, 100.0, row.ErrorBudgetConsumed)
+	assert.False(t, row.Pass)
+
+	row = newReportCheckRow(testCheck(1, "api", "up"), nil, from, testNow, 99)
+	assert.Equal(t, 100.0, row.Availability)
+	assert.Equal(t, 0.0, row.ErrorBudgetConsumed)
+	assert.True(t, row.Pass)
+}
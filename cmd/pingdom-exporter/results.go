@@ -0,0 +1,274 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	resultsEndpoint = "/results/{id}"
+
+	// resultsInitialWindow is how far back the raw test results of a check
+	// are retrieved the first time.
+	resultsInitialWindow = time.Hour
+
+	// resultsLimit is the maximum number of results the Pingdom API returns
+	// per request.
+	resultsLimit = 1000
+
+	// defaultResultURLTemplate links to the uptime report of a check.
+	defaultResultURLTemplate = "https://my.pingdom.com/app/reports/uptime#check={check_id}"
+)
+
+// responseTimeBuckets are the buckets of the response time histogram, in
+// seconds.
+var responseTimeBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// listResults retrieves all the raw test results of the check within the
+// given period, newest first, paging through them as the Pingdom API returns
+// at most resultsLimit results per request.
+func listResults(client *pingdom.Client, checkID int, from, to int64) ([]pingdom.ResultsResponseResult, error) {
+	var results []pingdom.ResultsResponseResult
+	seen := map[resultKey]bool{}
+
+	for to >= from {
+		page, err := client.Results.List(checkID, map[string]string{
+			"from":  strconv.FormatInt(from, 10),
+			"to":    strconv.FormatInt(to, 10),
+			"limit": strconv.Itoa(resultsLimit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range page {
+			if key := newResultKey(result); !seen[key] {
+				seen[key] = true
+				results = append(results, result)
+			}
+		}
+
+		if len(page) < resultsLimit {
+			break
+		}
+
+		// The next page includes the last second of this one, since the
+		// results of other probes within that second may not fit in it
+		next := page[len(page)-1].Time
+		if next == to {
+			// The whole page is within a single second
+			next--
+		}
+		to = next
+	}
+
+	return results, nil
+}
+
+// resultKey identifies a raw test result, as several probes may test a check
+// within the same second.
+type resultKey struct {
+	time    int64
+	probeID int
+}
+
+func newResultKey(result pingdom.ResultsResponseResult) resultKey {
+	return resultKey{result.Time, result.ProbeID}
+}
+
+// resultCursor tracks the raw test results of a check already observed, as
+// the results are retrieved again from the second of the last ones observed:
+// other probes may test the check within that second after it's retrieved.
+type resultCursor struct {
+	// time is the second from which results are new, and probes the ones
+	// whose result within that second was already observed.
+	time   int64
+	probes map[int]bool
+}
+
+// newResultCursor returns a cursor for which the results from the given time
+// on are new.
+func newResultCursor(from time.Time) resultCursor {
+	return resultCursor{time: from.Unix(), probes: map[int]bool{}}
+}
+
+// next returns true if the result wasn't observed yet, marking it as
+// observed. The results must be given oldest first.
+func (c *resultCursor) next(result pingdom.ResultsResponseResult) bool {
+	switch {
+	case result.Time < c.time:
+		return false
+	case result.Time == c.time:
+		if c.probes[result.ProbeID] {
+			return false
+		}
+	default:
+		c.time = result.Time
+		clear(c.probes)
+	}
+
+	c.probes[result.ProbeID] = true
+	return true
+}
+
+// resultHistory accumulates the raw test results of a check retrieved since
+// the exporter started, so their response times are exported as a proper
+// cumulative histogram.
+type resultHistory struct {
+	created time.Time
+	cursor  resultCursor
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+
+	// exemplars holds the latest result of every bucket, by bucket index,
+	// with the last one for the +Inf bucket.
+	exemplars []*prometheus.Exemplar
+}
+
+func newResultHistory(created time.Time) *resultHistory {
+	h := &resultHistory{
+		created:   created,
+		cursor:    newResultCursor(created),
+		buckets:   make(map[float64]uint64, len(responseTimeBuckets)),
+		exemplars: make([]*prometheus.Exemplar, len(responseTimeBuckets)+1),
+	}
+	for _, bound := range responseTimeBuckets {
+		h.buckets[bound] = 0
+	}
+	return h
+}
+
+// observe adds the results newer than the ones already observed. The
+// response time of failed tests is not observed, since it only reflects
+// timeouts. The exemplars link to the given URL template, in which
+// {check_id}, {probe_id} and {time} are replaced with the ones of the result.
+func (h *resultHistory) observe(checkID int, results []pingdom.ResultsResponseResult, urlTemplate string) {
+	// Results are returned newest first
+	for i := len(results) - 1; i >= 0; i-- {
+		result := results[i]
+		if !h.cursor.next(result) {
+			continue
+		}
+
+		if result.Status != "up" {
+			continue
+		}
+
+		value := float64(result.ResponseTime) / 1000.0
+		h.count++
+		h.sum += value
+
+		bucket := len(responseTimeBuckets)
+		for j := len(responseTimeBuckets) - 1; j >= 0 && value <= responseTimeBuckets[j]; j-- {
+			h.buckets[responseTimeBuckets[j]]++
+			bucket = j
+		}
+
+		url := strings.NewReplacer(
+			"{check_id}", strconv.Itoa(checkID),
+			"{probe_id}", strconv.Itoa(result.ProbeID),
+			"{time}", strconv.FormatInt(result.Time, 10),
+		).Replace(urlTemplate)
+
+		h.exemplars[bucket] = &prometheus.Exemplar{
+			Value:     value,
+			Labels:    prometheus.Labels{"probe_id": strconv.Itoa(result.ProbeID), "url": url},
+			Timestamp: time.Unix(result.Time, 0),
+		}
+	}
+}
+
+// eventCounter counts the raw test results of a check retrieved since the
+// exporter started, as the events of an event-based SLI. As outageCounter, it
+// starts with the results within the outage check period when the check is
+// first seen, counting since the beginning of that period.
+type eventCounter struct {
+	created time.Time
+	cursor  resultCursor
+
+	// good counts the successful tests, and total the successful and failed
+	// ones. Other results, i.e. unconfirmed_down or unknown, are not counted,
+	// as they are not accounted for in the up and down time either.
+	good  float64
+	total float64
+}
+
+func newEventCounter(created time.Time) *eventCounter {
+	return &eventCounter{created: created, cursor: newResultCursor(created)}
+}
+
+// observe counts the results newer than the ones already counted.
+func (c *eventCounter) observe(results []pingdom.ResultsResponseResult) {
+	// Results are returned newest first
+	for i := len(results) - 1; i >= 0; i-- {
+		result := results[i]
+		if !c.cursor.next(result) {
+			continue
+		}
+
+		switch result.Status {
+		case "up":
+			c.good++
+			c.total++
+		case "down":
+			c.total++
+		}
+	}
+}
+
+// metric returns the response time histogram, along with the exemplars. If
+// the exemplars are invalid, i.e. their labels are too long, the histogram is
+// returned without them, along with the error.
+func (h *resultHistory) metric(desc *prometheus.Desc, labelValues ...string) (prometheus.Metric, error) {
+	buckets := make(map[float64]uint64, len(h.buckets))
+	for bound, count := range h.buckets {
+		buckets[bound] = count
+	}
+
+	m, err := prometheus.NewConstHistogramWithCreatedTimestamp(desc, h.count, h.sum, buckets, h.created, labelValues...)
+	if err != nil {
+		return nil, err
+	}
+
+	var exemplars []prometheus.Exemplar
+	for _, e := range h.exemplars {
+		if e != nil {
+			exemplars = append(exemplars, *e)
+		}
+	}
+	if len(exemplars) == 0 {
+		return m, nil
+	}
+
+	withExemplars, err := prometheus.NewMetricWithExemplars(m, exemplars...)
+	if err != nil {
+		return m, err
+	}
+	return sortedExemplarLabels{withExemplars}, nil
+}
+
+// sortedExemplarLabels sorts the labels of the exemplars of a metric, which
+// prometheus.NewMetricWithExemplars writes in random order.
+type sortedExemplarLabels struct {
+	prometheus.Metric
+}
+
+func (m sortedExemplarLabels) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	for _, bucket := range out.GetHistogram().GetBucket() {
+		if e := bucket.GetExemplar(); e != nil {
+			slices.SortFunc(e.Label, func(a, b *dto.LabelPair) int {
+				return strings.Compare(a.GetName(), b.GetName())
+			})
+		}
+	}
+	return nil
+}
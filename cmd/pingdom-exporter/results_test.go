@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListResults(t *testing.T) {
+	// A full page of results ends within a second tested by three probes
+	var results []pingdom.ResultsResponseResult
+	for i := int64(0); i < resultsLimit-1; i++ {
+		results = append(results, pingdom.ResultsResponseResult{ProbeID: 1, Time: 2000 + i, Status: "up"})
+	}
+	for probeID := 1; probeID <= 3; probeID++ {
+		results = append(results, pingdom.ResultsResponseResult{ProbeID: probeID, Time: 1000, Status: "up"})
+	}
+
+	server := pingdomtest.NewServer(pingdomtest.Scenario{
+		Checks:  []pingdom.CheckResponse{testCheck(1, "api", "up")},
+		Results: map[int][]pingdom.ResultsResponseResult{1: results},
+	})
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	listed, err := listResults(client, 1, 0, 3000)
+	require.NoError(t, err)
+	assert.Len(t, listed, len(results))
+	assert.ElementsMatch(t, results, listed)
+
+	// The next page includes the last second of the first one
+	assert.Equal(t, []string{
+		"/results/1?from=0&limit=1000&to=3000",
+		"/results/1?from=0&limit=1000&to=1000",
+	}, server.Requests())
+}
+
+func TestResultCursor(t *testing.T) {
+	result := func(probeID int, time int64) pingdom.ResultsResponseResult {
+		return pingdom.ResultsResponseResult{ProbeID: probeID, Time: time}
+	}
+
+	cursor := newResultCursor(time.Unix(100, 0))
+	assert.False(t, cursor.next(result(1, 99)))
+	assert.True(t, cursor.next(result(1, 100)))
+	assert.True(t, cursor.next(result(1, 110)))
+	assert.False(t, cursor.next(result(1, 110)))
+
+	// Results of other probes within the same second are new, even once
+	// retrieved again
+	assert.True(t, cursor.next(result(2, 110)))
+	assert.False(t, cursor.next(result(2, 110)))
+	assert.Equal(t, int64(110), cursor.time)
+
+	assert.True(t, cursor.next(result(2, 120)))
+	assert.True(t, cursor.next(result(1, 120)))
+	assert.False(t, cursor.next(result(1, 110)))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Reasons for dropping series.
+const (
+	droppedLimit     = "limit"
+	droppedDuplicate = "duplicate"
+)
+
+// seriesSelector selects the metric families and labels exposed by the
+// exporter, and caps the number of series exposed per scrape. It applies to
+// all the metrics gathered from a registry, so the collectors don't need to
+// be aware of it.
+type seriesSelector struct {
+	includeMetrics []string
+	excludeMetrics []string
+	includeLabels  []string
+	excludeLabels  []string
+	maxSeries      int
+	logger         *slog.Logger
+
+	// droppedSeries is registered in its own registry, as it is updated
+	// while gathering the other metrics.
+	droppedSeries *prometheus.CounterVec
+	registry      *prometheus.Registry
+}
+
+// newSeriesSelector returns a selector for the metric families and labels
+// matching the given glob patterns. When no include patterns are given, all
+// metric families or labels are included. A maxSeries of zero disables the
+// series limit.
+func newSeriesSelector(includeMetrics, excludeMetrics, includeLabels, excludeLabels []string, maxSeries int) (*seriesSelector, error) {
+	for _, pattern := range slices.Concat(includeMetrics, excludeMetrics, includeLabels, excludeLabels) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	if maxSeries < 0 {
+		return nil, fmt.Errorf("invalid series limit %d", maxSeries)
+	}
+
+	s := &seriesSelector{
+		includeMetrics: includeMetrics,
+		excludeMetrics: excludeMetrics,
+		includeLabels:  includeLabels,
+		excludeLabels:  excludeLabels,
+		maxSeries:      maxSeries,
+		logger:         slog.Default(),
+		droppedSeries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pingdom_dropped_series_total",
+			Help: "Number of series dropped because of the series limit (limit) or because they were identical to another series once the excluded labels were removed (duplicate)",
+		}, []string{"reason"}),
+		registry: prometheus.NewRegistry(),
+	}
+
+	s.droppedSeries.WithLabelValues(droppedLimit)
+	s.droppedSeries.WithLabelValues(droppedDuplicate)
+	s.registry.MustRegister(s.droppedSeries)
+
+	return s, nil
+}
+
+// Gatherer returns a gatherer that applies the selection to the metrics
+// gathered by g.
+func (s *seriesSelector) Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		if families == nil {
+			return nil, err
+		}
+
+		selected, dropped := s.selectFamilies(families)
+		if dropped[droppedLimit] > 0 {
+			s.logger.Warn("Series limit exceeded, dropping series", "limit", s.maxSeries, "dropped", dropped[droppedLimit])
+		}
+		for reason, n := range dropped {
+			s.droppedSeries.WithLabelValues(reason).Add(n)
+		}
+
+		return prometheus.Gatherers{
+			prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return selected, err }),
+			s.registry,
+		}.Gather()
+	})
+}
+
+// selectFamilies returns the selected metric families, without the excluded
+// labels and within the series limit, along with the number of series dropped
+// by reason.
+func (s *seriesSelector) selectFamilies(families []*dto.MetricFamily) ([]*dto.MetricFamily, map[string]float64) {
+	dropped := map[string]float64{}
+	selected := make([]*dto.MetricFamily, 0, len(families))
+	series := 0
+
+	for _, family := range families {
+		if !s.selectedMetric(family.GetName()) {
+			continue
+		}
+
+		seen := map[string]bool{}
+		metrics := make([]*dto.Metric, 0, len(family.Metric))
+
+		for _, metric := range family.Metric {
+			labels := make([]*dto.LabelPair, 0, len(metric.Label))
+			for _, label := range metric.Label {
+				if s.selectedLabel(label.GetName()) {
+					labels = append(labels, label)
+				}
+			}
+
+			key := labelsKey(labels)
+			if seen[key] {
+				dropped[droppedDuplicate] += float64(seriesCount(family.GetType(), metric))
+				continue
+			}
+			seen[key] = true
+
+			n := seriesCount(family.GetType(), metric)
+			if s.maxSeries > 0 && series+n > s.maxSeries {
+				dropped[droppedLimit] += float64(n)
+				continue
+			}
+			series += n
+
+			metric.Label = labels
+			metrics = append(metrics, metric)
+		}
+
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			selected = append(selected, family)
+		}
+	}
+
+	return selected, dropped
+}
+
+// selectedMetric returns true if the metric family with the given name is
+// exposed.
+func (s *seriesSelector) selectedMetric(name string) bool {
+	return s.selected(name, s.includeMetrics, s.excludeMetrics)
+}
+
+// selectedLabel returns true if the label with the given name is exposed.
+func (s *seriesSelector) selectedLabel(name string) bool {
+	return s.selected(name, s.includeLabels, s.excludeLabels)
+}
+
+// selected returns true if the name matches any of the include patterns, or
+// there are none, and doesn't match any of the exclude patterns.
+func (s *seriesSelector) selected(name string, include, exclude []string) bool {
+	if len(include) > 0 && !matchAny(include, name) {
+		return false
+	}
+	return !matchAny(exclude, name)
+}
+
+// matchAny returns true if the name matches any of the glob patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// labelsKey returns a key identifying a series by its labels, which are
+// sorted by name.
+func labelsKey(labels []*dto.LabelPair) string {
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString(label.GetName())
+		b.WriteByte(0)
+		b.WriteString(label.GetValue())
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// seriesCount returns the number of series a metric is stored as by
+// Prometheus, i.e. one per bucket plus the sum and count for histograms.
+func seriesCount(t dto.MetricType, metric *dto.Metric) int {
+	switch t {
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		// The +Inf bucket is implicit
+		return len(metric.GetHistogram().GetBucket()) + 3
+	case dto.MetricType_SUMMARY:
+		return len(metric.GetSummary().GetQuantile()) + 2
+	}
+	return 1
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSeriesSelector(t *testing.T) {
+	_, err := newSeriesSelector(nil, []string{"pingdom_["}, nil, nil, 0)
+	assert.EqualError(t, err, `invalid pattern "pingdom_[": syntax error in pattern`)
+
+	_, err = newSeriesSelector(nil, nil, nil, nil, -1)
+	assert.EqualError(t, err, "invalid series limit -1")
+}
+
+func TestSeriesSelector(t *testing.T) {
+	newRegistry := func() *prometheus.Registry {
+		registry := prometheus.NewPedanticRegistry()
+
+		status := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_status", Help: "Test status."}, []string{"id", "hostname"})
+		status.WithLabelValues("1", "api.example.com").Set(1)
+		status.WithLabelValues("2", "web.example.com").Set(0)
+		status.WithLabelValues("2", "www.example.com").Set(0)
+
+		duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_duration_seconds", Help: "Test duration.", Buckets: []float64{1, 10}})
+		duration.Observe(5)
+
+		registry.MustRegister(status, duration)
+		return registry
+	}
+
+	testCases := []struct {
+		name           string
+		includeMetrics []string
+		excludeMetrics []string
+		includeLabels  []string
+		excludeLabels  []string
+		maxSeries      int
+		expected       string
+	}{
+		{
+			name: "all",
+			expected: `
+# HELP pingdom_dropped_series_total Number of series dropped because of the series limit (limit) or because they were identical to another series once the excluded labels were removed (duplicate)
+# TYPE pingdom_dropped_series_total counter
+pingdom_dropped_series_total{reason="duplicate"} 0
+pingdom_dropped_series_total{reason="limit"} 0
+# HELP test_duration_seconds Test duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="1"} 0
+test_duration_seconds_bucket{le="10"} 1
+test_duration_seconds_bucket{le="+Inf"} 1
+test_duration_seconds_sum 5
+test_duration_seconds_count 1
+# HELP test_status Test status.
+# TYPE test_status gauge
+test_status{hostname="api.example.com",id="1"} 1
+test_status{hostname="web.example.com",id="2"} 0
+test_status{hostname="www.example.com",id="2"} 0
+`,
+		},
+		{
+			name:           "metric families",
+			includeMetrics: []string{"test_*"},
+			excludeMetrics: []string{"*_seconds"},
+			expected: `
+# HELP pingdom_dropped_series_total Number of series dropped because of the series limit (limit) or because they were identical to another series once the excluded labels were removed (duplicate)
+# TYPE pingdom_dropped_series_total counter
+pingdom_dropped_series_total{reason="duplicate"} 0
+pingdom_dropped_series_total{reason="limit"} 0
+# HELP test_status Test status.
+# TYPE test_status gauge
+test_status{hostname="api.example.com",id="1"} 1
+test_status{hostname="web.example.com",id="2"} 0
+test_status{hostname="www.example.com",id="2"} 0
+`,
+		},
+		{
+			name:          "labels",
+			excludeLabels: []string{"host*"},
+			expected: `
+# HELP pingdom_dropped_series_total Number of series dropped because of the series limit (limit) or because they were identical to another series once the excluded labels were removed (duplicate)
+# TYPE pingdom_dropped_series_total counter
+pingdom_dropped_series_total{reason="duplicate"} 1
+pingdom_dropped_series_total{reason="limit"} 0
+# HELP test_duration_seconds Test duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="1"} 0
+test_duration_seconds_bucket{le="10"} 1
+test_duration_seconds_bucket{le="+Inf"} 1
+test_duration_seconds_sum 5
+test_duration_seconds_count 1
+# HELP test_status Test status.
+# TYPE test_status gauge
+test_status{id="1"} 1
+test_status{id="2"} 0
+`,
+		},
+		{
+			name:          "include labels",
+			includeLabels: []string{"hostname"},
+			expected: `
+# HELP pingdom_dropped_series_total Number of series dropped because of the series limit (limit) or because they were identical to another series once the excluded labels were removed (duplicate)
+# TYPE pingdom_dropped_series_total counter
+pingdom_dropped_series_total{reason="duplicate"} 0
+pingdom_dropped_series_total{reason="limit"} 0
+# HELP test_duration_seconds Test duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="1"} 0
+test_duration_seconds_bucket{le="10"} 1
+test_duration_seconds_bucket{le="+Inf"} 1
+test_duration_seconds_sum 5
+test_duration_seconds_count 1
+# HELP test_status Test status.
+# TYPE test_status gauge
+test_status{hostname="api.example.com"} 1
+test_status{hostname="web.example.com"} 0
+test_status{hostname="www.example.com"} 0
+`,
+		},
+		{
+			name:      "series limit",
+			maxSeries: 6,
+			expected: `
+# HELP pingdom_dropped_series_total Number of series dropped because of the series limit (limit) or because they were identical to another series once the excluded labels were removed (duplicate)
+# TYPE pingdom_dropped_series_total counter
+pingdom_dropped_series_total{reason="duplicate"} 0
+pingdom_dropped_series_total{reason="limit"} 2
+# HELP test_duration_seconds Test duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="1"} 0
+test_duration_seconds_bucket{le="10"} 1
+test_duration_seconds_bucket{le="+Inf"} 1
+test_duration_seconds_sum 5
+test_duration_seconds_count 1
+# HELP test_status Test status.
+# TYPE test_status gauge
+test_status{hostname="api.example.com",id="1"} 1
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			selector, err := newSeriesSelector(testCase.includeMetrics, testCase.excludeMetrics, testCase.includeLabels, testCase.excludeLabels, testCase.maxSeries)
+			require.NoError(t, err)
+
+			gatherer := selector.Gatherer(newRegistry())
+			assert.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(testCase.expected)))
+		})
+	}
+}
@@ -1,33 +1,103 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Server is the object that implements the HTTP server for the exporter.
 type Server struct {
-	mux *http.ServeMux
+	mux         *http.ServeMux
+	metricsPath string
+	ready       func() bool
 }
 
-// NewServer returns a new HTTP server for exposing Prometheus metrics.
-func NewServer() *Server {
+// NewServer returns a new HTTP server for exposing the Prometheus metrics
+// gathered from the given gatherer under metricsPath, in the OpenMetrics
+// format when negotiated, which includes exemplars and created timestamps.
+// The server reports itself as ready once the ready function returns true.
+func NewServer(gatherer prometheus.Gatherer, metricsPath string, ready func() bool, logger *slog.Logger) *Server {
 	s := &Server{
-		mux: http.NewServeMux(),
+		mux:         http.NewServeMux(),
+		metricsPath: metricsPath,
+		ready:       ready,
 	}
 
 	s.mux.HandleFunc("/healthz", s.healthz)
-	s.mux.Handle("/metrics", promhttp.Handler())
+	s.mux.HandleFunc("/ready", s.readyz)
+	s.mux.Handle(metricsPath, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		ErrorLog:                            slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		EnableOpenMetrics:                   true,
+		EnableOpenMetricsTextCreatedSamples: true,
+	}))
+	s.mux.HandleFunc("/", s.index)
 
 	return s
 }
 
+// Handle registers an additional endpoint of the exporter, i.e. /explain.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
 // ServeHTTP handles incoming HTTP requests.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
 }
 
+// healthz reports whether the exporter is alive.
 func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
+
+// readyz reports whether the exporter is able to retrieve data from Pingdom.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready() {
+		http.Error(w, "Not Ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("OK"))
+}
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Write([]byte(`<html>
+             <head><title>Pingdom Exporter</title></head>
+             <body>
+             <h1>Pingdom Exporter</h1>
+             <p><a href='` + s.metricsPath + `'>Metrics</a></p>
+             </body>
+             </html>`))
+}
+
+// serve runs the given HTTP server until the context is canceled, then shuts
+// it down gracefully, waiting up to shutdownTimeout for in-flight requests,
+// i.e. ongoing scrapes, to complete.
+func serve(ctx context.Context, srv *http.Server, listen func() error, shutdownTimeout time.Duration, logger *slog.Logger) error {
+	shutdownErr := make(chan error, 1)
+
+	go func() {
+		<-ctx.Done()
+		logger.Info("Shutting down, waiting for in-flight requests to complete", "timeout", shutdownTimeout)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := listen(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return <-shutdownErr
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "Test gauge."}))
+
+	ready := false
+	server := NewServer(registry, "/custom-metrics", func() bool { return ready }, slog.Default())
+
+	testCases := []struct {
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"/healthz", http.StatusOK, "OK"},
+		{"/ready", http.StatusServiceUnavailable, "Not Ready\n"},
+		{"/custom-metrics", http.StatusOK, "# HELP test_gauge Test gauge.\n# TYPE test_gauge gauge\ntest_gauge 0\n"},
+		{"/metrics", http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for _, testCase := range testCases {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", testCase.path, nil))
+		assert.Equal(t, testCase.expectedStatus, rec.Code, testCase.path)
+		assert.Equal(t, testCase.expectedBody, rec.Body.String(), testCase.path)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<a href='/custom-metrics'>")
+
+	ready = true
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServerOpenMetrics(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test counter."})
+	counter.(prometheus.ExemplarAdder).AddWithExemplar(1, prometheus.Labels{"url": "https://example.com"})
+	registry.MustRegister(counter)
+
+	server := NewServer(registry, "/metrics", func() bool { return true }, slog.Default())
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/openmetrics-text")
+	assert.Regexp(t, `test_total 1.0 # \{url="https://example.com"\} 1.0 \d+`, rec.Body.String())
+	assert.Regexp(t, `test_created \d+`, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "# EOF\n")
+}
+
+func TestServeWaitsForInFlightRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("done"))
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() {
+		served <- serve(ctx, srv, func() error { return srv.Serve(listener) }, time.Second, slog.Default())
+	}()
+
+	body := make(chan string)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if !assert.NoError(t, err) {
+			close(body)
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, "done", <-body)
+	assert.NoError(t, <-served)
+}
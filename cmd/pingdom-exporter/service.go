@@ -0,0 +1,193 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+// Aggregation modes of a service, see service.downTime.
+const (
+	// The service is down whenever any of its checks is down.
+	serviceModeAllUp = "all_up"
+
+	// The service is down only when all of its checks are down.
+	serviceModeAnyUp = "any_up"
+
+	// The service is down by the weighted fraction of its checks which are
+	// down, i.e. half down when a check of weight 1 out of 2 is.
+	serviceModeWeighted = "weighted"
+)
+
+// service is a composite service made of several checks, i.e. its API, web
+// and CDN checks, whose availability and error budget are computed from the
+// merged outage states of the checks.
+type service struct {
+	name      string
+	mode      string
+	uptimeSLO float64
+
+	// The checks of the service are the ones matching the selector or in the
+	// check list.
+	selector checkSelector
+	checks   []int
+
+	// Weights of the checks, by check id, in the weighted mode; defaults to 1.
+	weights map[int]float64
+}
+
+// interval is a period of time, as Unix timestamps.
+type interval struct {
+	from, to int64
+}
+
+// newServices validates the services of the configuration file, using the
+// default uptime SLO for the ones without one.
+func newServices(cfgs []serviceConfig, defaultUptimeSLO float64) ([]*service, error) {
+	var services []*service
+
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("service must have a name")
+		}
+		if slices.ContainsFunc(services, func(s *service) bool { return s.name == cfg.Name }) {
+			return nil, fmt.Errorf("duplicate service %q", cfg.Name)
+		}
+
+		s := &service{
+			name:      cfg.Name,
+			mode:      cmp.Or(cfg.Mode, serviceModeAllUp),
+			uptimeSLO: cmp.Or(cfg.UptimeSLO, defaultUptimeSLO),
+			checks:    cfg.Checks,
+			weights:   cfg.Weights,
+		}
+
+		if cfg.Selector == "" && len(cfg.Checks) == 0 {
+			return nil, fmt.Errorf("service %q must have a selector or a list of checks", cfg.Name)
+		}
+		if cfg.Selector != "" {
+			selector, err := parseCheckSelector(cfg.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("service %q: %w", cfg.Name, err)
+			}
+			s.selector = selector
+		}
+
+		switch s.mode {
+		case serviceModeAllUp, serviceModeAnyUp:
+			if len(cfg.Weights) > 0 {
+				return nil, fmt.Errorf("service %q can only have weights in the %s mode", cfg.Name, serviceModeWeighted)
+			}
+		case serviceModeWeighted:
+			for id, weight := range cfg.Weights {
+				if weight <= 0 {
+					return nil, fmt.Errorf("service %q: weight of check %d must be positive", cfg.Name, id)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("service %q: invalid mode %q (one of: %s, %s, %s)", cfg.Name, s.mode, serviceModeAllUp, serviceModeAnyUp, serviceModeWeighted)
+		}
+
+		if s.uptimeSLO <= 0 || s.uptimeSLO > 100 {
+			return nil, fmt.Errorf("service %q: uptime SLO %s%% is out of range", cfg.Name, formatFloat(s.uptimeSLO))
+		}
+
+		services = append(services, s)
+	}
+
+	return services, nil
+}
+
+// Match returns true if the check is part of the service.
+func (s *service) Match(check pingdom.CheckResponse) bool {
+	return slices.Contains(s.checks, check.ID) || s.selector != nil && s.selector.Match(check)
+}
+
+// weight returns the weight of the check within the service.
+func (s *service) weight(checkID int) float64 {
+	if weight, ok := s.weights[checkID]; ok {
+		return weight
+	}
+	return 1
+}
+
+// downTime returns the down time of the service, in seconds, given the down
+// intervals of its checks, by check id. Overlapping outages of several checks
+// are merged according to the mode of the service, so i.e. two checks down at
+// the same time only count once in the all_up mode.
+func (s *service) downTime(downIntervals map[int][]interval) float64 {
+	type boundary struct {
+		time   int64
+		weight float64
+		count  int
+	}
+
+	var (
+		boundaries  []boundary
+		totalWeight float64
+	)
+	for id, intervals := range downIntervals {
+		weight := s.weight(id)
+		totalWeight += weight
+		for _, i := range mergeIntervals(intervals) {
+			boundaries = append(boundaries, boundary{i.from, weight, 1}, boundary{i.to, -weight, -1})
+		}
+	}
+	if len(downIntervals) == 0 {
+		return 0
+	}
+
+	slices.SortFunc(boundaries, func(a, b boundary) int {
+		return cmp.Compare(a.time, b.time)
+	})
+
+	var (
+		downTime   float64
+		downWeight float64
+		downCount  int
+	)
+	for i, b := range boundaries {
+		if i > 0 && b.time > boundaries[i-1].time {
+			elapsed := float64(b.time - boundaries[i-1].time)
+			switch s.mode {
+			case serviceModeAllUp:
+				if downCount > 0 {
+					downTime += elapsed
+				}
+			case serviceModeAnyUp:
+				if downCount == len(downIntervals) {
+					downTime += elapsed
+				}
+			case serviceModeWeighted:
+				downTime += elapsed * downWeight / totalWeight
+			}
+		}
+		downWeight += b.weight
+		downCount += b.count
+	}
+
+	return downTime
+}
+
+// mergeIntervals returns the union of the intervals, sorted.
+func mergeIntervals(intervals []interval) []interval {
+	sorted := slices.Clone(intervals)
+	slices.SortFunc(sorted, func(a, b interval) int {
+		return cmp.Compare(a.from, b.from)
+	})
+
+	var merged []interval
+	for _, i := range sorted {
+		if i.to <= i.from {
+			continue
+		}
+		if n := len(merged); n > 0 && i.from <= merged[n-1].to {
+			merged[n-1].to = max(merged[n-1].to, i.to)
+			continue
+		}
+		merged = append(merged, i)
+	}
+	return merged
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServices(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfgs        []serviceConfig
+		expectedErr string
+	}{
+		{name: "selector", cfgs: []serviceConfig{{Name: "shop", Selector: "tag=shop"}}},
+		{name: "checks", cfgs: []serviceConfig{{Name: "shop", Checks: []int{1, 2}, Mode: "any_up", UptimeSLO: 99.9}}},
+		{name: "weights", cfgs: []serviceConfig{{Name: "shop", Checks: []int{1, 2}, Mode: "weighted", Weights: map[int]float64{1: 2}}}},
+		{name: "no name", cfgs: []serviceConfig{{Checks: []int{1}}}, expectedErr: "service must have a name"},
+		{name: "duplicate", cfgs: []serviceConfig{{Name: "shop", Checks: []int{1}}, {Name: "shop", Checks: []int{2}}}, expectedErr: `duplicate service "shop"`},
+		{name: "no checks", cfgs: []serviceConfig{{Name: "shop"}}, expectedErr: `service "shop" must have a selector or a list of checks`},
+		{name: "invalid selector", cfgs: []serviceConfig{{Name: "shop", Selector: "owner=me"}}, expectedErr: `service "shop": invalid check selector "owner=me": unknown field "owner"`},
+		{name: "invalid mode", cfgs: []serviceConfig{{Name: "shop", Checks: []int{1}, Mode: "some_up"}}, expectedErr: `service "shop": invalid mode "some_up" (one of: all_up, any_up, weighted)`},
+		{name: "unexpected weights", cfgs: []serviceConfig{{Name: "shop", Checks: []int{1}, Weights: map[int]float64{1: 2}}}, expectedErr: `service "shop" can only have weights in the weighted mode`},
+		{name: "invalid weight", cfgs: []serviceConfig{{Name: "shop", Checks: []int{1}, Mode: "weighted", Weights: map[int]float64{1: 0}}}, expectedErr: `service "shop": weight of check 1 must be positive`},
+		{name: "invalid uptime SLO", cfgs: []serviceConfig{{Name: "shop", Checks: []int{1}, UptimeSLO: 101}}, expectedErr: `service "shop": uptime SLO 101% is out of range`},
+	}
+
+	for _, testCase := range testCases {
+		services, err := newServices(testCase.cfgs, 99)
+		if testCase.expectedErr != "" {
+			assert.EqualError(t, err, testCase.expectedErr, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		assert.Len(t, services, len(testCase.cfgs), testCase.name)
+	}
+
+	services, err := newServices([]serviceConfig{{Name: "shop", Selector: "team=shop", Checks: []int{3}}}, 99)
+	require.NoError(t, err)
+	assert.Equal(t, serviceModeAllUp, services[0].mode)
+	assert.Equal(t, 99.0, services[0].uptimeSLO)
+	assert.True(t, services[0].Match(testCheck(1, "api", "up", "team:shop")))
+	assert.True(t, services[0].Match(testCheck(3, "cdn", "up")))
+	assert.False(t, services[0].Match(testCheck(2, "web", "up", "team:blog")))
+}
+
+func TestServiceDownTime(t *testing.T) {
+	// The API is down from 100 to 200 and from 150 to 300 as reported by
+	// overlapping states, the web from 250 to 400, and the CDN never
+	downIntervals := map[int][]interval{
+		1: {{100, 200}, {150, 300}},
+		2: {{250, 400}},
+		3: nil,
+	}
+
+	testCases := []struct {
+		mode     string
+		weights  map[int]float64
+		expected float64
+	}{
+		{mode: serviceModeAllUp, expected: 300},
+		{mode: serviceModeAnyUp, expected: 0},
+		{mode: serviceModeWeighted, expected: (200.0 + 150.0) / 3},
+		{mode: serviceModeWeighted, weights: map[int]float64{1: 2, 3: 0.5}, expected: (2*200.0 + 150.0) / 3.5},
+	}
+
+	for _, testCase := range testCases {
+		s := &service{name: "shop", mode: testCase.mode, weights: testCase.weights}
+		assert.InDelta(t, testCase.expected, s.downTime(downIntervals), 1e-9, testCase.mode)
+	}
+
+	// Without the CDN, both checks are down from 250 to 300
+	delete(downIntervals, 3)
+	s := &service{name: "shop", mode: serviceModeAnyUp}
+	assert.Equal(t, 50.0, s.downTime(downIntervals))
+	assert.Equal(t, 0.0, s.downTime(nil))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// tokenEnvVars are the environment variables from which the Pingdom API token
+// is read when no token file is given, in order of precedence.
+var tokenEnvVars = []string{"PINGDOM_API_TOKEN", "PINGDOM_TOKEN"}
+
+// tokenFromEnv returns the Pingdom API token from the first non-empty
+// environment variable in tokenEnvVars.
+func tokenFromEnv() string {
+	for _, name := range tokenEnvVars {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// readTokenFile reads the Pingdom API token from the given file, i.e. a
+// mounted Kubernetes secret, ignoring surrounding whitespace.
+func readTokenFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+
+	return token, nil
+}
+
+// tokenSetter is implemented by pingdom.Client.
+type tokenSetter interface {
+	CurrentToken() string
+	SetToken(token string)
+}
+
+// watchTokenFile re-reads the token file every interval until the context is
+// canceled, replacing the token of the client when it changes so a rotated
+// secret is picked up without restarting the exporter.
+func watchTokenFile(ctx context.Context, path string, interval time.Duration, client tokenSetter, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloadTokenFile(path, client, logger)
+		}
+	}
+}
+
+// reloadTokenFile reads the token file once, keeping the current token if the
+// file cannot be read.
+func reloadTokenFile(path string, client tokenSetter, logger *slog.Logger) {
+	token, err := readTokenFile(path)
+	if err != nil {
+		logger.Error("Cannot reload Pingdom API token, keeping the current one", "file", path, "err", err)
+		return
+	}
+
+	if token != client.CurrentToken() {
+		client.SetToken(token)
+		logger.Info("Reloaded Pingdom API token", "file", path)
+	}
+}
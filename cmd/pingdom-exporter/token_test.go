@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenFromEnv(t *testing.T) {
+	t.Setenv("PINGDOM_API_TOKEN", "")
+	t.Setenv("PINGDOM_TOKEN", "")
+	assert.Equal(t, "", tokenFromEnv())
+
+	t.Setenv("PINGDOM_TOKEN", "fallback")
+	assert.Equal(t, "fallback", tokenFromEnv())
+
+	t.Setenv("PINGDOM_API_TOKEN", "primary")
+	assert.Equal(t, "primary", tokenFromEnv())
+}
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+
+	_, err := readTokenFile(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(" \n"), 0600))
+	_, err = readTokenFile(path)
+	assert.EqualError(t, err, "token file "+path+" is empty")
+
+	require.NoError(t, os.WriteFile(path, []byte("secret\n"), 0600))
+	token, err := readTokenFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", token)
+}
+
+func TestWatchTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0600))
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"checks":[]}`)
+	}))
+	defer server.Close()
+
+	client, err := pingdom.NewClientWithConfig(pingdom.ClientConfig{Token: "old", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchTokenFile(ctx, path, 10*time.Millisecond, client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	require.NoError(t, os.WriteFile(path, []byte("new"), 0600))
+	assert.Eventually(t, func() bool { return client.CurrentToken() == "new" }, time.Second, 10*time.Millisecond)
+
+	// Requests use the new token
+	_, _, err = client.Checks.List()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer new", authorization)
+
+	// A missing file keeps the current token
+	require.NoError(t, os.Remove(path))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "new", client.CurrentToken())
+}
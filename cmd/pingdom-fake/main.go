@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+)
+
+var (
+	port         int
+	scenarioFile string
+)
+
+func init() {
+	flag.IntVar(&port, "port", 9159, "port to listen on")
+	flag.StringVar(&scenarioFile, "scenario", "", "JSON file describing the checks, outages, results and faults to be served (defaults to a built-in example scenario)")
+}
+
+// exampleScenario returns a small scenario with an healthy check, a check with
+// an ongoing outage and a paused check, whose outages are relative to now.
+func exampleScenario(now time.Time) pingdomtest.Scenario {
+	ts := func(d time.Duration) int64 {
+		return now.Add(-d).Unix()
+	}
+	day := 24 * time.Hour
+
+	// One result per minute over the last two hours, newest first, from
+	// alternating probes
+	var results []pingdom.ResultsResponseResult
+	for i := 0; i < 120; i++ {
+		results = append(results, pingdom.ResultsResponseResult{
+			ProbeID:      63 + i%3,
+			Time:         ts(time.Duration(i) * time.Minute),
+			Status:       "up",
+			ResponseTime: int64(100 + (i*37)%400),
+			StatusDesc:   "OK",
+		})
+	}
+
+	return pingdomtest.Scenario{
+		Checks: []pingdom.CheckResponse{
+			{
+				ID:               1,
+				Name:             "Example API",
+				Hostname:         "api.example.com",
+				Resolution:       1,
+				Status:           "up",
+				LastTestTime:     ts(time.Minute),
+				LastResponseTime: 120,
+				Type:             pingdom.CheckResponseType{Name: "http"},
+				Tags: []pingdom.CheckResponseTag{
+					{Name: "uptime_slo_999", Type: "u", Count: 1},
+				},
+			},
+			{
+				ID:               2,
+				Name:             "Example Website",
+				Hostname:         "www.example.com",
+				Resolution:       5,
+				Status:           "down",
+				LastTestTime:     ts(time.Minute),
+				LastErrorTime:    ts(time.Minute),
+				LastResponseTime: 0,
+				Type:             pingdom.CheckResponseType{Name: "http"},
+			},
+			{
+				ID:         3,
+				Name:       "Example Legacy",
+				Hostname:   "legacy.example.com",
+				Resolution: 5,
+				Status:     "paused",
+				Type:       pingdom.CheckResponseType{Name: "ping"},
+			},
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{
+			1: {
+				{Status: "up", FromTime: ts(60 * day), ToTime: ts(2 * day)},
+				{Status: "down", FromTime: ts(2 * day), ToTime: ts(2*day - 10*time.Minute)},
+				{Status: "up", FromTime: ts(2*day - 10*time.Minute), ToTime: ts(0)},
+			},
+			2: {
+				{Status: "up", FromTime: ts(60 * day), ToTime: ts(time.Hour)},
+				{Status: "down", FromTime: ts(time.Hour), ToTime: ts(0)},
+			},
+		},
+		Results: map[int][]pingdom.ResultsResponseResult{
+			1: results,
+		},
+		RateLimit: &pingdomtest.RateLimit{
+			Short:      12000,
+			ShortReset: pingdomtest.Duration(time.Hour),
+			Long:       48000,
+			LongReset:  pingdomtest.Duration(24 * time.Hour),
+		},
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	scenario := exampleScenario(time.Now())
+	if scenarioFile != "" {
+		s, err := pingdomtest.LoadScenario(scenarioFile)
+		if err != nil {
+			slog.Error("Cannot load scenario, exiting", "err", err)
+			os.Exit(1)
+		}
+		scenario = *s
+	}
+
+	slog.Info("Fake Pingdom API listening", "address", fmt.Sprintf("http://0.0.0.0:%v", port))
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), pingdomtest.NewHandler(scenario)); err != nil {
+		slog.Error("Cannot serve HTTP requests, exiting", "err", err)
+		os.Exit(1)
+	}
+}
@@ -3,10 +3,7 @@ package pingdom
 import (
 	"encoding/json"
 	"fmt"
-	"math"
-	"os"
 	"regexp"
-	"strconv"
 	"strings"
 )
 
@@ -102,6 +99,34 @@ type SummaryPerformanceSummary struct {
 	Uptime      int `json:"uptime"`
 }
 
+// ResultsResponse represents the JSON response for a list of raw test results from the Pingdom API.
+type ResultsResponse struct {
+	ActiveProbes []int                   `json:"activeprobes"`
+	Results      []ResultsResponseResult `json:"results"`
+}
+
+// ResultsResponseResult represents the JSON response for each raw test result.
+type ResultsResponseResult struct {
+	ProbeID        int    `json:"probeid"`
+	Time           int64  `json:"time"`
+	Status         string `json:"status"`
+	ResponseTime   int64  `json:"responsetime"`
+	StatusDesc     string `json:"statusdesc"`
+	StatusDescLong string `json:"statusdesclong"`
+}
+
+// MarshalJSON converts a CheckResponseType into the same representation used
+// by the Pingdom API, i.e. a plain string unless type details are present.
+func (c CheckResponseType) MarshalJSON() ([]byte, error) {
+	switch {
+	case c.HTTP != nil:
+		return json.Marshal(map[string]interface{}{c.Name: c.HTTP})
+	case c.TCP != nil:
+		return json.Marshal(map[string]interface{}{c.Name: c.TCP})
+	}
+	return json.Marshal(c.Name)
+}
+
 // UnmarshalJSON converts a byte array into a CheckResponseType.
 func (c *CheckResponseType) UnmarshalJSON(b []byte) error {
 	var raw interface{}
@@ -187,22 +212,26 @@ func (cr *CheckResponse) HasIgnoreTag() bool {
 // i.e. "uptime_slo_999" for 99.9 uptime SLO. Returns the argument as the
 // default uptime SLO in case no uptime SLO tag exists for this check.
 func (cr *CheckResponse) UptimeSLOFromTags(defaultUptimeSLO float64) float64 {
-	for _, tag := range cr.Tags {
-		matches := uptimeSLORegexp.FindStringSubmatch(tag.Name)
-
-		if len(matches) > 0 {
-			n, err := strconv.ParseFloat(matches[1], 64)
+	if _, slo, ok := cr.UptimeSLOTag(); ok {
+		return slo
+	}
 
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing uptime SLO tag %s: %v", matches[1], err)
-				break
-			}
+	return defaultUptimeSLO
+}
 
-			return n / math.Pow(10, math.Max(0, float64(len(matches[1])-2)))
+// UptimeSLOTag returns the tag the uptime SLO of this check is configured
+// with, along with the uptime SLO. Returns false in case no valid uptime SLO
+// tag exists for this check. Unlike UptimeSLO, the first valid tag wins and
+// conflicting tags aren't reported.
+func (cr *CheckResponse) UptimeSLOTag() (string, float64, bool) {
+	for _, tag := range cr.Tags {
+		slo := (&CheckResponse{Tags: []CheckResponseTag{tag}}).UptimeSLO(0, 0)
+		if slo.Source == UptimeSLOSourceTag {
+			return slo.Tag, slo.Value, true
 		}
 	}
 
-	return defaultUptimeSLO
+	return "", 0, false
 }
 
 // private types used to unmarshall JSON responses from Pingdom.
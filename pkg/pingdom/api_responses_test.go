@@ -1,6 +1,7 @@
 package pingdom
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -139,6 +140,14 @@ func TestCheckResponseUptimeSLOFromTags(t *testing.T) {
 			},
 			expectedUptimeSLO: 9,
 		},
+		{
+			tag: CheckResponseTag{
+				Name:  "uptime_slo_0",
+				Type:  "a",
+				Count: 2,
+			},
+			expectedUptimeSLO: 91,
+		},
 		{
 			tag: CheckResponseTag{
 				Name:  "no_uptime_slo_tag",
@@ -158,3 +167,54 @@ func TestCheckResponseUptimeSLOFromTags(t *testing.T) {
 		assert.Equal(t, uptimeSLO, testCase.expectedUptimeSLO)
 	}
 }
+
+func TestCheckResponseUptimeSLOTag(t *testing.T) {
+	response := CheckResponse{
+		Tags: []CheckResponseTag{{Name: "payments"}, {Name: "uptime_slo_0"}, {Name: "uptime_slo_9995"}},
+	}
+
+	tag, uptimeSLO, ok := response.UptimeSLOTag()
+	assert.True(t, ok)
+	assert.Equal(t, "uptime_slo_9995", tag)
+	assert.Equal(t, 99.95, uptimeSLO)
+
+	response.Tags = response.Tags[:2]
+	_, _, ok = response.UptimeSLOTag()
+	assert.False(t, ok)
+}
+
+func TestCheckResponseTypeMarshalJSON(t *testing.T) {
+	testCases := []struct {
+		checkType CheckResponseType
+		expected  string
+	}{
+		{
+			checkType: CheckResponseType{Name: "http"},
+			expected:  `"http"`,
+		},
+		{
+			checkType: CheckResponseType{
+				Name: "http",
+				HTTP: &CheckResponseHTTPDetails{URL: "/health"},
+			},
+			expected: `{"http":{"url":"/health"}}`,
+		},
+		{
+			checkType: CheckResponseType{
+				Name: "tcp",
+				TCP:  &CheckResponseTCPDetails{Port: 22},
+			},
+			expected: `{"tcp":{"port":22}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		b, err := json.Marshal(testCase.checkType)
+		assert.NoError(t, err)
+		assert.JSONEq(t, testCase.expected, string(b))
+
+		var actual CheckResponseType
+		assert.NoError(t, json.Unmarshal(b, &actual))
+		assert.Equal(t, testCase.checkType, actual)
+	}
+}
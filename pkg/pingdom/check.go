@@ -35,7 +35,7 @@ func (cs *CheckService) List(params ...map[string]string) ([]CheckResponse, floa
 		return nil, 0, err
 	}
 
-	resp, err := cs.client.client.Do(req)
+	resp, err := cs.client.send(req, "/checks", 0)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -0,0 +1,130 @@
+package fixtures
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom/pingdomtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderReplayer(t *testing.T) {
+	scenario := pingdomtest.Scenario{
+		Token: "my_secret_token",
+		Checks: []pingdom.CheckResponse{
+			{
+				ID:       1,
+				Name:     "Check 1",
+				Hostname: "example.com",
+				Status:   "up",
+				Type: pingdom.CheckResponseType{
+					Name: "http",
+					HTTP: &pingdom.CheckResponseHTTPDetails{
+						URL:            "/health?token=my_secret_token",
+						Username:       "admin",
+						Password:       "hunter2",
+						RequestHeaders: map[string]string{"X-Api-Key": "abc"},
+					},
+				},
+				Tags: []pingdom.CheckResponseTag{
+					{Name: "apache", Type: "u", Count: float64(1)},
+				},
+			},
+			{
+				ID:       2,
+				Name:     "Check 2",
+				Hostname: "example.net",
+				Status:   "down",
+				Type:     pingdom.CheckResponseType{Name: "ping"},
+				Tags: []pingdom.CheckResponseTag{
+					{Name: "nginx", Type: "u", Count: float64(1)},
+				},
+			},
+		},
+		Outages: map[int][]pingdom.OutageSummaryResponseState{
+			1: {
+				{Status: "up", FromTime: 100, ToTime: 200},
+				{Status: "down", FromTime: 200, ToTime: 300},
+				{Status: "up", FromTime: 300, ToTime: 400},
+			},
+		},
+	}
+
+	server := pingdomtest.NewServer(scenario)
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	recordingClient, err := server.NewClient(pingdom.ClientConfig{
+		Token:      "my_secret_token",
+		HTTPClient: &http.Client{Transport: NewRecorder(dir, nil)},
+	})
+	require.NoError(t, err)
+
+	recordedChecks, _, err := recordingClient.Checks.List(map[string]string{"include_tags": "true"})
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", recordedChecks[0].Type.HTTP.Password)
+
+	recordedStates, err := recordingClient.OutageSummary.List(1, map[string]string{"from": "150", "to": "350"})
+	require.NoError(t, err)
+
+	_, err = recordingClient.OutageSummary.List(3)
+	require.Error(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	for _, file := range files {
+		b, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		require.NoError(t, err)
+		assert.NotContains(t, string(b), "my_secret_token")
+		assert.NotContains(t, string(b), "hunter2")
+		assert.NotContains(t, string(b), "admin")
+		assert.NotContains(t, string(b), "abc")
+	}
+
+	// The fake API is no longer needed to replay the responses
+	server.Close()
+
+	replayingClient, err := pingdom.NewClientWithConfig(pingdom.ClientConfig{
+		BaseURL:    "https://api.pingdom.com/api/3.1",
+		HTTPClient: &http.Client{Transport: NewReplayer(dir)},
+	})
+	require.NoError(t, err)
+
+	checks, _, err := replayingClient.Checks.List(map[string]string{"include_tags": "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, "REDACTED", checks[0].Type.HTTP.Password)
+	assert.Equal(t, "/health?token=REDACTED", checks[0].Type.HTTP.URL)
+	assert.Equal(t, map[string]string{"X-Api-Key": "REDACTED"}, checks[0].Type.HTTP.RequestHeaders)
+	assert.Equal(t, recordedChecks[1], checks[1])
+
+	// The outage window bounds are not part of the fixture key
+	states, err := replayingClient.OutageSummary.List(1, map[string]string{"from": "200", "to": "400"})
+	assert.NoError(t, err)
+	assert.Equal(t, recordedStates, states)
+
+	_, err = replayingClient.OutageSummary.List(3)
+	assert.Equal(t, &pingdom.Error{StatusCode: 404, StatusDesc: "Not Found", Message: "Check not found"}, err)
+
+	_, err = replayingClient.OutageSummary.List(4)
+	assert.ErrorContains(t, err, "no fixture for GET /api/3.1/summary.outage/4")
+}
+
+func TestScrubBody(t *testing.T) {
+	assert.JSONEq(t,
+		`{"contacts":[{"name":"John","email":"REDACTED","notification_targets":{"sms":[{"number":"REDACTED","countrycode":"REDACTED"}]}}]}`,
+		string(scrubBody([]byte(`{"contacts":[{"name":"John","email":"john@example.com","notification_targets":{"sms":[{"number":"5555","countrycode":"55"}]}}]}`), "")),
+	)
+	assert.JSONEq(t,
+		`{"contacts":[{"id":1,"notification_targets":{"sms":[{"number":0,"countrycode":0,"provider":"nexmo"}]}}]}`,
+		string(scrubBody([]byte(`{"contacts":[{"id":1,"notification_targets":{"sms":[{"number":5551234567,"countrycode":55,"provider":"nexmo"}]}}]}`), "")),
+	)
+	assert.Equal(t, `"Bad Gateway REDACTED"`, string(scrubBody([]byte("Bad Gateway my_secret_token"), "my_secret_token")))
+	assert.Equal(t, `"Bad Gateway xyz"`, string(scrubBody([]byte("Bad Gateway xyz"), "x")))
+}
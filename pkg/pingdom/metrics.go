@@ -0,0 +1,93 @@
+package pingdom
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clientMetrics holds the metrics about the requests made to the Pingdom API.
+type clientMetrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+// newClientMetrics creates the Pingdom API client metrics and registers them
+// with the given registerer.
+func newClientMetrics(reg prometheus.Registerer) (*clientMetrics, error) {
+	labels := []string{"endpoint", "code"}
+
+	m := &clientMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pingdom_api_requests_total",
+			Help: "Number of requests made to the Pingdom API, by endpoint and HTTP status code (none: no response received)",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pingdom_api_request_duration_seconds",
+			Help:    "Duration of the requests made to the Pingdom API until the response headers are received, in seconds",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, labels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pingdom_api_response_size_bytes",
+			Help:    "Size of the response bodies returned by the Pingdom API, in bytes",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		}, labels),
+	}
+
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.responseSize} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// observe records a request to the given endpoint template. The response may
+// be nil when no response was received, otherwise its body is wrapped so the
+// response size is recorded once the body is closed.
+func (m *clientMetrics) observe(endpoint string, resp *http.Response, duration time.Duration) {
+	code := "none"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+
+	m.requests.WithLabelValues(endpoint, code).Inc()
+	m.duration.WithLabelValues(endpoint, code).Observe(duration.Seconds())
+
+	if resp != nil {
+		size := m.responseSize.WithLabelValues(endpoint, code)
+		resp.Body = &countingBody{
+			ReadCloser: resp.Body,
+			onClose: func(n int64) {
+				size.Observe(float64(n))
+			},
+		}
+	}
+}
+
+// countingBody is a response body that counts the bytes read from it.
+type countingBody struct {
+	io.ReadCloser
+	n       int64
+	onClose func(n int64)
+	closed  bool
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.onClose(b.n)
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,73 @@
+package pingdom
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientMetrics(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registry := prometheus.NewPedanticRegistry()
+	c, err := NewClientWithConfig(ClientConfig{
+		BaseURL:    server.URL,
+		Registerer: registry,
+	})
+	require.NoError(t, err)
+
+	mux.HandleFunc("/checks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"checks": []}`)
+	})
+	mux.HandleFunc("/summary.outage/1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"statuscode":404,"statusdesc":"Not Found","errormessage":"Not found"}}`)
+	})
+
+	_, _, err = c.Checks.List()
+	assert.NoError(t, err)
+	_, _, err = c.Checks.List()
+	assert.NoError(t, err)
+	_, err = c.OutageSummary.List(1)
+	assert.Error(t, err)
+
+	// Requests made via Do are recorded by endpoint template as well
+	req, err := c.NewRequest("GET", "/summary.outage/1", nil)
+	require.NoError(t, err)
+	_, err = c.Do(req, &struct{}{})
+	assert.Error(t, err)
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP pingdom_api_requests_total Number of requests made to the Pingdom API, by endpoint and HTTP status code (none: no response received)
+		# TYPE pingdom_api_requests_total counter
+		pingdom_api_requests_total{code="200",endpoint="/checks"} 2
+		pingdom_api_requests_total{code="404",endpoint="/summary.outage/{id}"} 2
+	`), "pingdom_api_requests_total"))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "pingdom_api_request_duration_seconds"))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "pingdom_api_response_size_bytes" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if m.GetLabel()[1].GetValue() == "/checks" {
+				assert.EqualValues(t, 2, m.GetHistogram().GetSampleCount())
+				assert.EqualValues(t, 2*len(`{"checks": []}`), m.GetHistogram().GetSampleSum())
+			}
+		}
+	}
+
+	// Registering the same metrics twice fails
+	_, err = NewClientWithConfig(ClientConfig{Registerer: registry})
+	assert.Error(t, err)
+}
@@ -23,7 +23,7 @@ func (os *OutageSummaryService) List(checkID int, params ...map[string]string) (
 		return nil, err
 	}
 
-	resp, err := os.client.client.Do(req)
+	resp, err := os.client.send(req, "/summary.outage/{id}", checkID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,43 @@
+package pingdom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// SummaryPerformanceService provides an interface to Pingdom performance
+// summary.
+type SummaryPerformanceService struct {
+	client *Client
+}
+
+// List returns the performance summary of a check from Pingdom, broken down
+// by hour, day or week according to the resolution parameter.
+func (ps *SummaryPerformanceService) List(checkID int, params ...map[string]string) (SummaryPerformanceMap, error) {
+	param := map[string]string{}
+	if len(params) == 1 {
+		param = params[0]
+	}
+
+	req, err := ps.client.NewRequest("GET", fmt.Sprintf("/summary.performance/%d", checkID), param)
+	if err != nil {
+		return SummaryPerformanceMap{}, err
+	}
+
+	resp, err := ps.client.send(req, "/summary.performance/{id}", checkID)
+	if err != nil {
+		return SummaryPerformanceMap{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := validateResponse(resp); err != nil {
+		return SummaryPerformanceMap{}, err
+	}
+
+	bodyBytes, _ := ioutil.ReadAll(resp.Body)
+	m := &SummaryPerformanceResponse{}
+	err = json.Unmarshal(bodyBytes, &m)
+
+	return m.Summary, err
+}
@@ -0,0 +1,45 @@
+package pingdom
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryPerformanceServiceList(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/summary.performance/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		assert.Equal(t, "hour", r.URL.Query().Get("resolution"))
+		fmt.Fprint(w, `{
+			"summary": {
+				"hours": [
+					{
+						"starttime": 1294178400,
+						"avgresponse": 230,
+						"uptime": 3540,
+						"downtime": 60,
+						"unmonitored": 0
+					}
+				]
+			}
+		}`)
+	})
+
+	want := SummaryPerformanceMap{
+		Hours: []SummaryPerformanceSummary{
+			{StartTime: 1294178400, AvgResponse: 230, Uptime: 3540, Downtime: 60},
+		},
+	}
+
+	summary, err := client.SummaryPerformance.List(1, map[string]string{
+		"resolution": "hour",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, want, summary)
+}
@@ -4,8 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -16,14 +24,23 @@ const (
 // provides a NewClient function for convenience to initialize a client
 // with default parameters.
 type Client struct {
+	// Token is the API token the client was created with. It's not updated
+	// by SetToken, so use CurrentToken to read the token in use.
 	Token   string
 	BaseURL *url.URL
 	client  *http.Client
+	logger  *slog.Logger
+	metrics *clientMetrics
+
+	token      atomic.Pointer[string]
+	authFailed atomic.Bool
 
 	Tags string
 
-	Checks        *CheckService
-	OutageSummary *OutageSummaryService
+	Checks             *CheckService
+	OutageSummary      *OutageSummaryService
+	SummaryPerformance *SummaryPerformanceService
+	Results            *ResultsService
 }
 
 // ClientConfig represents a configuration for a pingdom client.
@@ -32,6 +49,14 @@ type ClientConfig struct {
 	Tags       string
 	BaseURL    string
 	HTTPClient *http.Client
+
+	// Logger is used to log every request made to the Pingdom API, at debug
+	// level. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	// Registerer, when set, is used to register metrics about the requests
+	// made to the Pingdom API, by endpoint and HTTP status code.
+	Registerer prometheus.Registerer
 }
 
 // NewClientWithConfig returns a Pingdom client.
@@ -59,12 +84,49 @@ func NewClientWithConfig(config ClientConfig) (*Client, error) {
 		c.client = http.DefaultClient
 	}
 
+	if config.Logger != nil {
+		c.logger = config.Logger
+	} else {
+		c.logger = slog.Default()
+	}
+
+	if config.Registerer != nil {
+		c.metrics, err = newClientMetrics(config.Registerer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	c.Checks = &CheckService{client: c}
 	c.OutageSummary = &OutageSummaryService{client: c}
+	c.SummaryPerformance = &SummaryPerformanceService{client: c}
+	c.Results = &ResultsService{client: c}
 
 	return c, nil
 }
 
+// CurrentToken returns the API token used to authenticate the requests: the
+// last one set via SetToken, or Token if none was.
+func (pc *Client) CurrentToken() string {
+	if token := pc.token.Load(); token != nil {
+		return *token
+	}
+	return pc.Token
+}
+
+// SetToken replaces the API token used to authenticate the requests. It is
+// safe to call while requests are in flight, i.e. to rotate the token.
+func (pc *Client) SetToken(token string) {
+	pc.token.Store(&token)
+}
+
+// AuthFailed returns true if the last response from the Pingdom API was a
+// 401 Unauthorized or 403 Forbidden error, meaning the API token is invalid,
+// expired or lacks permissions.
+func (pc *Client) AuthFailed() bool {
+	return pc.authFailed.Load()
+}
+
 // NewRequest makes a new HTTP Request.  The method param should be an HTTP method in
 // all caps such as GET, POST, PUT, DELETE.  The rsc param should correspond with
 // a restful resource.  Params can be passed in as a map of strings
@@ -86,7 +148,7 @@ func (pc *Client) NewRequest(method string, rsc string, params map[string]string
 	}
 
 	req, err := http.NewRequest(method, baseURL.String(), nil)
-	req.Header.Add("Authorization", "Bearer "+pc.Token)
+	req.Header.Add("Authorization", "Bearer "+pc.CurrentToken())
 
 	return req, err
 }
@@ -95,7 +157,7 @@ func (pc *Client) NewRequest(method string, rsc string, params map[string]string
 // passed in interface.  If the HTTP response is outside of the 2xx range the
 // response will be returned along with the error.
 func (pc *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	resp, err := pc.client.Do(req)
+	resp, err := pc.send(req, endpointTemplate(strings.TrimPrefix(req.URL.Path, pc.BaseURL.Path)), 0)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +171,64 @@ func (pc *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 	return resp, err
 }
 
+// endpointTemplate returns the endpoint of the given request path with its
+// IDs replaced by "{id}", as in the endpoints of the typed services, i.e.
+// "/summary.outage/{id}" for "/summary.outage/123", so the request metrics
+// don't have a series per check.
+func endpointTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// send makes an HTTP request to the given endpoint, which may be a template
+// such as "/summary.outage/{id}" in which case checkID holds the check ID. The
+// request is logged at debug level along with its status code, duration and
+// the remaining rate limit, and recorded in the client metrics, if enabled.
+func (pc *Client) send(req *http.Request, endpoint string, checkID int) (*http.Response, error) {
+	start := time.Now()
+	resp, err := pc.client.Do(req)
+	duration := time.Since(start)
+
+	attrs := []any{"method", req.Method, "endpoint", endpoint}
+	if checkID != 0 {
+		attrs = append(attrs, "check_id", checkID)
+	}
+	attrs = append(attrs, "duration", duration)
+
+	if pc.metrics != nil {
+		pc.metrics.observe(endpoint, resp, duration)
+	}
+
+	if err != nil {
+		pc.logger.Debug("Pingdom API request failed", append(attrs, "err", err)...)
+		return nil, err
+	}
+
+	attrs = append(attrs, "status", resp.StatusCode)
+	if limit := minRequestLimitFromHeader(resp.Header); limit != math.MaxFloat64 {
+		attrs = append(attrs, "rate_limit_remaining", limit)
+	}
+	pc.logger.Debug("Pingdom API request", attrs...)
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		if !pc.authFailed.Swap(true) {
+			pc.logger.Error("Pingdom API rejected the API token, make sure it is valid and has read access to the checks", "status", resp.StatusCode)
+		}
+	default:
+		if pc.authFailed.Swap(false) {
+			pc.logger.Info("Pingdom API accepted the API token again")
+		}
+	}
+
+	return resp, nil
+}
+
 func decodeResponse(r *http.Response, v interface{}) error {
 	if v == nil {
 		return fmt.Errorf("nil interface provided to decodeResponse")
@@ -132,8 +252,13 @@ func validateResponse(r *http.Response) error {
 	bodyString := string(bodyBytes)
 	m := &errorJSONResponse{}
 	err := json.Unmarshal([]byte(bodyString), &m)
-	if err != nil {
-		return err
+	if err != nil || m.Error == nil {
+		// Not a Pingdom API error, i.e. an error page from a proxy
+		return &Error{
+			StatusCode: r.StatusCode,
+			StatusDesc: http.StatusText(r.StatusCode),
+			Message:    bodyString,
+		}
 	}
 
 	return m.Error
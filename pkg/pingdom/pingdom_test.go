@@ -1,8 +1,11 @@
 package pingdom
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -46,6 +49,7 @@ func TestNewClientWithConfig(t *testing.T) {
 	})
 	assert.NoError(t, err)
 	assert.Equal(t, http.DefaultClient, c.client)
+	assert.Equal(t, slog.Default(), c.logger)
 	assert.Equal(t, defaultBaseURL, c.BaseURL.String())
 	assert.NotNil(t, c.Checks)
 }
@@ -84,6 +88,95 @@ func TestDo(t *testing.T) {
 	assert.Equal(t, want, body)
 }
 
+func TestEndpointTemplate(t *testing.T) {
+	assert.Equal(t, "/checks", endpointTemplate("/checks"))
+	assert.Equal(t, "/checks/{id}", endpointTemplate("/checks/123"))
+	assert.Equal(t, "/results/{id}", endpointTemplate("/results/456"))
+	assert.Equal(t, "/maintenance/{id}/checks/{id}", endpointTemplate("/maintenance/7/checks/8"))
+	assert.Equal(t, "/summary.outage/{id}", endpointTemplate("/summary.outage/123"))
+}
+
+func TestSendLogsRequests(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var buf bytes.Buffer
+	client.logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mux.HandleFunc("/summary.outage/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("req-limit-short", "Remaining: 12 Time until reset: 34")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"statuscode":404,"statusdesc":"Not Found","errormessage":"Not found"}}`)
+	})
+
+	_, err := client.OutageSummary.List(1)
+	assert.Error(t, err)
+
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "DEBUG", entry["level"])
+	assert.Equal(t, "Pingdom API request", entry["msg"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/summary.outage/{id}", entry["endpoint"])
+	assert.EqualValues(t, 1, entry["check_id"])
+	assert.EqualValues(t, 404, entry["status"])
+	assert.EqualValues(t, 12, entry["rate_limit_remaining"])
+	assert.Contains(t, entry, "duration")
+}
+
+func TestSetToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var authorization string
+	mux.HandleFunc("/checks", func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"checks":[]}`)
+	})
+
+	assert.Equal(t, client.Token, client.CurrentToken())
+
+	client.SetToken("rotated")
+	assert.Equal(t, "rotated", client.CurrentToken())
+
+	_, _, err := client.Checks.List()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer rotated", authorization)
+}
+
+func TestAuthFailed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var buf bytes.Buffer
+	client.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	status := http.StatusUnauthorized
+	mux.HandleFunc("/checks", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"checks":[]}`)
+	})
+
+	assert.False(t, client.AuthFailed())
+
+	_, _, err := client.Checks.List()
+	assert.Error(t, err)
+	assert.True(t, client.AuthFailed())
+	assert.Contains(t, buf.String(), "Pingdom API rejected the API token")
+
+	// The error is logged only once until the token is accepted again
+	buf.Reset()
+	status = http.StatusForbidden
+	client.Checks.List()
+	assert.True(t, client.AuthFailed())
+	assert.Empty(t, buf.String())
+
+	status = http.StatusOK
+	_, _, err = client.Checks.List()
+	assert.NoError(t, err)
+	assert.False(t, client.AuthFailed())
+}
+
 func TestValidateResponse(t *testing.T) {
 	valid := &http.Response{
 		Request:    &http.Request{},
@@ -107,4 +200,22 @@ func TestValidateResponse(t *testing.T) {
 
 	want := &Error{400, "Bad Request", "This is an error"}
 	assert.Equal(t, want, validateResponse(invalid))
+
+	notJSON := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusBadGateway,
+		Body:       ioutil.NopCloser(strings.NewReader("<html>Bad Gateway</html>")),
+	}
+
+	want = &Error{502, "Bad Gateway", "<html>Bad Gateway</html>"}
+	assert.Equal(t, want, validateResponse(notJSON))
+
+	noError := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+	}
+
+	want = &Error{503, "Service Unavailable", "{}"}
+	assert.Equal(t, want, validateResponse(noError))
 }
@@ -0,0 +1,546 @@
+// Package pingdomtest provides an in-process fake of the Pingdom API, serving
+// checks, outage summaries, performance summaries and raw results from a
+// scripted scenario. It is meant to be used by tests and for running the
+// exporter locally without network access.
+package pingdomtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+)
+
+var (
+	outageSummaryPathRe      = regexp.MustCompile(`^/summary\.outage/(\d+)$`)
+	performanceSummaryPathRe = regexp.MustCompile(`^/summary\.performance/(\d+)$`)
+	resultsPathRe            = regexp.MustCompile(`^/results/(\d+)$`)
+)
+
+// Scenario describes the data served by the fake Pingdom API.
+type Scenario struct {
+	// Token, when set, is the only bearer token accepted by the fake API.
+	// Requests with a different token are answered with 401 Unauthorized.
+	Token string `json:"token,omitempty"`
+
+	Checks      []pingdom.CheckResponse                      `json:"checks"`
+	Outages     map[int][]pingdom.OutageSummaryResponseState `json:"outages,omitempty"`
+	Performance map[int]pingdom.SummaryPerformanceMap        `json:"performance,omitempty"`
+	Results     map[int][]pingdom.ResultsResponseResult      `json:"results,omitempty"`
+	RateLimit   *RateLimit                                   `json:"ratelimit,omitempty"`
+	Faults      []Fault                                      `json:"faults,omitempty"`
+}
+
+// RateLimit describes the short-term and long-term rate limits reported by
+// the fake API via the req-limit-short and req-limit-long headers. Every
+// request consumes one unit of both limits; once any of them reaches zero the
+// fake API answers with 429 Too Many Requests until the limit is reset.
+type RateLimit struct {
+	Short      int      `json:"short"`
+	ShortReset Duration `json:"shortreset"`
+	Long       int      `json:"long"`
+	LongReset  Duration `json:"longreset"`
+}
+
+// Fault describes an error or delay to be injected in the fake API responses.
+type Fault struct {
+	// Path is the request path prefix this fault applies to, i.e. "/checks"
+	// or "/summary.outage/123". An empty path matches every request.
+	Path string `json:"path,omitempty"`
+
+	// Status is the HTTP status code to answer with. Zero means the request
+	// is served normally, which is useful to only inject latency.
+	Status int `json:"status,omitempty"`
+
+	// Message is the error message returned in the response body.
+	Message string `json:"message,omitempty"`
+
+	// Latency is the delay applied before answering the request.
+	Latency Duration `json:"latency,omitempty"`
+
+	// Count is the number of requests affected by this fault. Zero means the
+	// fault never expires.
+	Count int `json:"count,omitempty"`
+}
+
+// Duration is a time.Duration that is represented in JSON as a string such
+// as "250ms" or "1m".
+type Duration time.Duration
+
+// MarshalJSON converts a Duration into its string representation.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON parses a Duration from either a string such as "1m30s" or a
+// number of nanoseconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	switch v := raw.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", b)
+	}
+
+	return nil
+}
+
+// LoadScenario reads a JSON encoded scenario from the given file.
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scenario := &Scenario{}
+	if err := json.Unmarshal(b, scenario); err != nil {
+		return nil, fmt.Errorf("parsing scenario %s: %w", path, err)
+	}
+
+	return scenario, nil
+}
+
+// Handler is an http.Handler that implements the subset of the Pingdom API
+// used by the exporter.
+type Handler struct {
+	mu       sync.Mutex
+	scenario Scenario
+	faults   []*Fault
+	requests []string
+
+	shortRemaining, longRemaining int
+	shortResetAt, longResetAt     time.Time
+
+	now func() time.Time
+}
+
+// NewHandler returns a new Handler serving the given scenario.
+func NewHandler(scenario Scenario) *Handler {
+	h := &Handler{now: time.Now}
+	h.SetScenario(scenario)
+	return h
+}
+
+// SetScenario replaces the scenario served by this handler, resetting any
+// injected faults and rate limit counters.
+func (h *Handler) SetScenario(scenario Scenario) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.scenario = scenario
+	h.faults = nil
+	for _, fault := range scenario.Faults {
+		fault := fault
+		h.faults = append(h.faults, &fault)
+	}
+
+	if rl := scenario.RateLimit; rl != nil {
+		now := h.now()
+		h.shortRemaining, h.shortResetAt = rl.Short, now.Add(time.Duration(rl.ShortReset))
+		h.longRemaining, h.longResetAt = rl.Long, now.Add(time.Duration(rl.LongReset))
+	}
+}
+
+// InjectFault adds a fault to be applied to the subsequent requests. Faults
+// are evaluated in the order they were added.
+func (h *Handler) InjectFault(fault Fault) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.faults = append(h.faults, &fault)
+}
+
+// Requests returns the request URIs received so far, in order.
+func (h *Handler) Requests() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]string(nil), h.requests...)
+}
+
+// ServeHTTP handles incoming HTTP requests.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.requests = append(h.requests, r.URL.RequestURI())
+	fault := h.matchFault(r.URL.Path)
+	limited := h.consumeRateLimit(w.Header())
+	scenario := h.scenario
+	h.mu.Unlock()
+
+	if fault != nil && fault.Latency > 0 {
+		select {
+		case <-time.After(time.Duration(fault.Latency)):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if scenario.Token != "" && r.Header.Get("Authorization") != "Bearer "+scenario.Token {
+		writeError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	if limited {
+		writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+		return
+	}
+
+	if fault != nil && fault.Status != 0 {
+		writeError(w, fault.Status, fault.Message)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+
+	if r.URL.Path == "/checks" {
+		writeJSON(w, map[string]interface{}{
+			"checks": listChecks(scenario.Checks, query),
+		})
+		return
+	}
+
+	if matches := outageSummaryPathRe.FindStringSubmatch(r.URL.Path); matches != nil {
+		id, _ := strconv.Atoi(matches[1])
+		if !hasCheck(scenario.Checks, id) {
+			writeError(w, http.StatusNotFound, "Check not found")
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"summary": pingdom.OutageSummaryResponse{
+				States: listOutages(scenario.Outages[id], query),
+			},
+		})
+		return
+	}
+
+	if matches := performanceSummaryPathRe.FindStringSubmatch(r.URL.Path); matches != nil {
+		id, _ := strconv.Atoi(matches[1])
+		if !hasCheck(scenario.Checks, id) {
+			writeError(w, http.StatusNotFound, "Check not found")
+			return
+		}
+		writeJSON(w, pingdom.SummaryPerformanceResponse{
+			Summary: listPerformance(scenario.Performance[id], query),
+		})
+		return
+	}
+
+	if matches := resultsPathRe.FindStringSubmatch(r.URL.Path); matches != nil {
+		id, _ := strconv.Atoi(matches[1])
+		if !hasCheck(scenario.Checks, id) {
+			writeError(w, http.StatusNotFound, "Check not found")
+			return
+		}
+		writeJSON(w, pingdom.ResultsResponse{
+			ActiveProbes: activeProbes(scenario.Results[id]),
+			Results:      listResults(scenario.Results[id], query),
+		})
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "Resource not found")
+}
+
+// matchFault returns the first active fault matching the given path,
+// consuming one of its occurrences. Must be called with h.mu held.
+func (h *Handler) matchFault(path string) *Fault {
+	for i, fault := range h.faults {
+		if !strings.HasPrefix(path, fault.Path) {
+			continue
+		}
+
+		matched := *fault
+		if fault.Count > 0 {
+			fault.Count--
+			if fault.Count == 0 {
+				h.faults = append(h.faults[:i:i], h.faults[i+1:]...)
+			}
+		}
+		return &matched
+	}
+
+	return nil
+}
+
+// consumeRateLimit sets the rate limit headers and returns true if the
+// request exceeds the rate limit. Must be called with h.mu held.
+func (h *Handler) consumeRateLimit(header http.Header) bool {
+	rl := h.scenario.RateLimit
+	if rl == nil {
+		return false
+	}
+
+	now := h.now()
+	if !now.Before(h.shortResetAt) {
+		h.shortRemaining, h.shortResetAt = rl.Short, now.Add(time.Duration(rl.ShortReset))
+	}
+	if !now.Before(h.longResetAt) {
+		h.longRemaining, h.longResetAt = rl.Long, now.Add(time.Duration(rl.LongReset))
+	}
+
+	limited := h.shortRemaining <= 0 || h.longRemaining <= 0
+	if !limited {
+		h.shortRemaining--
+		h.longRemaining--
+	}
+
+	header.Set("req-limit-short", fmt.Sprintf("Remaining: %d Time until reset: %d", h.shortRemaining, secondsUntil(now, h.shortResetAt)))
+	header.Set("req-limit-long", fmt.Sprintf("Remaining: %d Time until reset: %d", h.longRemaining, secondsUntil(now, h.longResetAt)))
+
+	return limited
+}
+
+// Server is a fake Pingdom API listening on a system-chosen port on the local
+// loopback interface, for use in end-to-end tests.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts and returns a new fake Pingdom API serving the given
+// scenario. The caller should call Close when finished, to shut it down.
+func NewServer(scenario Scenario) *Server {
+	handler := NewHandler(scenario)
+	return &Server{
+		Server:  httptest.NewServer(handler),
+		Handler: handler,
+	}
+}
+
+// NewClient returns a Pingdom client configured to talk to this server. The
+// base URL from the given config is ignored.
+func (s *Server) NewClient(config pingdom.ClientConfig) (*pingdom.Client, error) {
+	config.BaseURL = s.URL
+	if config.HTTPClient == nil {
+		config.HTTPClient = s.Client()
+	}
+	return pingdom.NewClientWithConfig(config)
+}
+
+func listChecks(checks []pingdom.CheckResponse, query map[string][]string) []pingdom.CheckResponse {
+	var tags []string
+	if v := first(query, "tags"); v != "" {
+		tags = strings.Split(v, ",")
+	}
+	includeTags := first(query, "include_tags") == "true"
+	includeSeverity := first(query, "include_severity") == "true"
+
+	filtered := []pingdom.CheckResponse{}
+	for _, check := range checks {
+		if len(tags) > 0 && !hasAnyTag(check, tags) {
+			continue
+		}
+		if !includeTags {
+			check.Tags = nil
+		}
+		if !includeSeverity {
+			check.SeverityLevel = ""
+		}
+		filtered = append(filtered, check)
+	}
+
+	return paginate(filtered, query)
+}
+
+func listOutages(states []pingdom.OutageSummaryResponseState, query map[string][]string) []pingdom.OutageSummaryResponseState {
+	from, to := timeRange(query)
+
+	// Pingdom clips the returned states to the requested time range.
+	clipped := []pingdom.OutageSummaryResponseState{}
+	for _, state := range states {
+		if state.ToTime <= from || state.FromTime >= to {
+			continue
+		}
+		if state.FromTime < from {
+			state.FromTime = from
+		}
+		if state.ToTime > to {
+			state.ToTime = to
+		}
+		clipped = append(clipped, state)
+	}
+
+	return clipped
+}
+
+func listPerformance(summary pingdom.SummaryPerformanceMap, query map[string][]string) pingdom.SummaryPerformanceMap {
+	from, to := timeRange(query)
+
+	filter := func(summaries []pingdom.SummaryPerformanceSummary) []pingdom.SummaryPerformanceSummary {
+		filtered := []pingdom.SummaryPerformanceSummary{}
+		for _, s := range summaries {
+			if int64(s.StartTime) >= from && int64(s.StartTime) < to {
+				filtered = append(filtered, s)
+			}
+		}
+		return filtered
+	}
+
+	switch first(query, "resolution") {
+	case "day":
+		return pingdom.SummaryPerformanceMap{Days: filter(summary.Days)}
+	case "week":
+		return pingdom.SummaryPerformanceMap{Weeks: filter(summary.Weeks)}
+	default:
+		return pingdom.SummaryPerformanceMap{Hours: filter(summary.Hours)}
+	}
+}
+
+func listResults(results []pingdom.ResultsResponseResult, query map[string][]string) []pingdom.ResultsResponseResult {
+	from, to := timeRange(query)
+
+	var statuses []string
+	if v := first(query, "status"); v != "" {
+		statuses = strings.Split(v, ",")
+	}
+
+	filtered := []pingdom.ResultsResponseResult{}
+	for _, result := range results {
+		if result.Time < from || result.Time > to {
+			continue
+		}
+		if len(statuses) > 0 && !contains(statuses, result.Status) {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+
+	// Pingdom returns the most recent results first.
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Time > filtered[j].Time
+	})
+
+	return paginate(filtered, query)
+}
+
+func activeProbes(results []pingdom.ResultsResponseResult) []int {
+	probes := []int{}
+	for _, result := range results {
+		if !containsInt(probes, result.ProbeID) {
+			probes = append(probes, result.ProbeID)
+		}
+	}
+	sort.Ints(probes)
+	return probes
+}
+
+func paginate[T any](items []T, query map[string][]string) []T {
+	offset, _ := strconv.Atoi(first(query, "offset"))
+	if offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+
+	if limit, err := strconv.Atoi(first(query, "limit")); err == nil && limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+
+	return items
+}
+
+// timeRange returns the time range requested via the "from" and "to" query
+// parameters, defaulting to an unbounded range.
+func timeRange(query map[string][]string) (int64, int64) {
+	var from, to int64 = 0, 1<<63 - 1
+
+	if v, err := strconv.ParseInt(first(query, "from"), 10, 64); err == nil {
+		from = v
+	}
+	if v, err := strconv.ParseInt(first(query, "to"), 10, 64); err == nil {
+		to = v
+	}
+
+	return from, to
+}
+
+func hasCheck(checks []pingdom.CheckResponse, id int) bool {
+	for _, check := range checks {
+		if check.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyTag(check pingdom.CheckResponse, tags []string) bool {
+	for _, tag := range check.Tags {
+		if contains(tags, tag.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func first(query map[string][]string, key string) string {
+	if values := query[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func secondsUntil(now, t time.Time) int64 {
+	if d := t.Sub(now); d > 0 {
+		return int64(d / time.Second)
+	}
+	return 0
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": pingdom.Error{
+			StatusCode: status,
+			StatusDesc: http.StatusText(status),
+			Message:    message,
+		},
+	})
+}
@@ -0,0 +1,220 @@
+package pingdomtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jusbrasil/pingdom-exporter/pkg/pingdom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testScenario = Scenario{
+	Checks: []pingdom.CheckResponse{
+		{
+			ID:       1,
+			Name:     "Check 1",
+			Hostname: "example.com",
+			Status:   "up",
+			Type:     pingdom.CheckResponseType{Name: "http"},
+			Tags: []pingdom.CheckResponseTag{
+				{Name: "apache", Type: "u", Count: float64(1)},
+			},
+		},
+		{
+			ID:       2,
+			Name:     "Check 2",
+			Hostname: "example.net",
+			Status:   "down",
+			Type:     pingdom.CheckResponseType{Name: "ping"},
+			Tags: []pingdom.CheckResponseTag{
+				{Name: "nginx", Type: "u", Count: float64(1)},
+			},
+		},
+	},
+	Outages: map[int][]pingdom.OutageSummaryResponseState{
+		1: {
+			{Status: "up", FromTime: 100, ToTime: 200},
+			{Status: "down", FromTime: 200, ToTime: 300},
+			{Status: "up", FromTime: 300, ToTime: 400},
+		},
+	},
+	Results: map[int][]pingdom.ResultsResponseResult{
+		1: {
+			{ProbeID: 2, Time: 100, Status: "up", ResponseTime: 120},
+			{ProbeID: 1, Time: 200, Status: "down", ResponseTime: 0},
+			{ProbeID: 2, Time: 300, Status: "up", ResponseTime: 80},
+		},
+	},
+}
+
+func TestServerChecks(t *testing.T) {
+	server := NewServer(testScenario)
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	checks, _, err := client.Checks.List(map[string]string{"include_tags": "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, testScenario.Checks, checks)
+
+	checks, _, err = client.Checks.List(map[string]string{"tags": "nginx"})
+	assert.NoError(t, err)
+	assert.Len(t, checks, 1)
+	assert.Equal(t, 2, checks[0].ID)
+	assert.Empty(t, checks[0].Tags)
+}
+
+func TestServerOutageSummary(t *testing.T) {
+	server := NewServer(testScenario)
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	states, err := client.OutageSummary.List(1, map[string]string{
+		"from": "150",
+		"to":   "350",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []pingdom.OutageSummaryResponseState{
+		{Status: "up", FromTime: 150, ToTime: 200},
+		{Status: "down", FromTime: 200, ToTime: 300},
+		{Status: "up", FromTime: 300, ToTime: 350},
+	}, states)
+
+	_, err = client.OutageSummary.List(3)
+	assert.Equal(t, &pingdom.Error{StatusCode: 404, StatusDesc: "Not Found", Message: "Check not found"}, err)
+}
+
+func TestServerResults(t *testing.T) {
+	server := NewServer(testScenario)
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	req, err := client.NewRequest("GET", "/results/1", map[string]string{
+		"status": "up",
+		"limit":  "1",
+	})
+	require.NoError(t, err)
+
+	results := &pingdom.ResultsResponse{}
+	_, err = client.Do(req, results)
+	assert.NoError(t, err)
+	assert.Equal(t, &pingdom.ResultsResponse{
+		ActiveProbes: []int{1, 2},
+		Results: []pingdom.ResultsResponseResult{
+			{ProbeID: 2, Time: 300, Status: "up", ResponseTime: 80},
+		},
+	}, results)
+}
+
+func TestServerToken(t *testing.T) {
+	scenario := testScenario
+	scenario.Token = "secret"
+
+	server := NewServer(scenario)
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{Token: "wrong"})
+	require.NoError(t, err)
+
+	_, _, err = client.Checks.List()
+	assert.Equal(t, &pingdom.Error{StatusCode: 401, StatusDesc: "Unauthorized", Message: "Invalid token"}, err)
+
+	client, err = server.NewClient(pingdom.ClientConfig{Token: "secret"})
+	require.NoError(t, err)
+
+	_, _, err = client.Checks.List()
+	assert.NoError(t, err)
+}
+
+func TestServerInjectFault(t *testing.T) {
+	server := NewServer(testScenario)
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	server.InjectFault(Fault{
+		Path:    "/summary.outage/1",
+		Status:  http.StatusInternalServerError,
+		Message: "Boom",
+		Count:   1,
+	})
+
+	_, err = client.OutageSummary.List(1)
+	assert.Equal(t, &pingdom.Error{StatusCode: 500, StatusDesc: "Internal Server Error", Message: "Boom"}, err)
+
+	_, _, err = client.Checks.List()
+	assert.NoError(t, err)
+
+	_, err = client.OutageSummary.List(1)
+	assert.NoError(t, err)
+
+	server.InjectFault(Fault{Latency: Duration(50 * time.Millisecond)})
+
+	start := time.Now()
+	_, _, err = client.Checks.List()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	assert.Equal(t, []string{
+		"/summary.outage/1",
+		"/checks",
+		"/summary.outage/1",
+		"/checks",
+	}, server.Requests())
+}
+
+func TestServerRateLimit(t *testing.T) {
+	scenario := testScenario
+	scenario.RateLimit = &RateLimit{
+		Short:      2,
+		ShortReset: Duration(time.Minute),
+		Long:       10,
+		LongReset:  Duration(time.Hour),
+	}
+
+	server := NewServer(scenario)
+	defer server.Close()
+
+	client, err := server.NewClient(pingdom.ClientConfig{})
+	require.NoError(t, err)
+
+	_, minReqLimit, err := client.Checks.List()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, minReqLimit)
+
+	_, minReqLimit, err = client.Checks.List()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, minReqLimit)
+
+	_, minReqLimit, err = client.Checks.List()
+	assert.Equal(t, &pingdom.Error{StatusCode: 429, StatusDesc: "Too Many Requests", Message: "Rate limit exceeded"}, err)
+	assert.EqualValues(t, 0, minReqLimit)
+}
+
+func TestLoadScenario(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	b, err := json.Marshal(testScenario)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+
+	scenario, err := LoadScenario(path)
+	assert.NoError(t, err)
+	assert.Equal(t, &testScenario, scenario)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"faults": [{"latency": "1s"}]}`), 0o600))
+
+	scenario, err = LoadScenario(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Duration(time.Second), scenario.Faults[0].Latency)
+}
@@ -0,0 +1,42 @@
+package pingdom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ResultsService provides an interface to Pingdom raw test results.
+type ResultsService struct {
+	client *Client
+}
+
+// List returns a list of raw test results of a check from Pingdom, newest
+// first.
+func (rs *ResultsService) List(checkID int, params ...map[string]string) ([]ResultsResponseResult, error) {
+	param := map[string]string{}
+	if len(params) == 1 {
+		param = params[0]
+	}
+
+	req, err := rs.client.NewRequest("GET", fmt.Sprintf("/results/%d", checkID), param)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rs.client.send(req, "/results/{id}", checkID)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := validateResponse(resp); err != nil {
+		return nil, err
+	}
+
+	bodyBytes, _ := ioutil.ReadAll(resp.Body)
+	m := &ResultsResponse{}
+	err = json.Unmarshal(bodyBytes, &m)
+
+	return m.Results, err
+}
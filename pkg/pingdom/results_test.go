@@ -0,0 +1,65 @@
+package pingdom
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultsServiceList(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/results/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		assert.Equal(t, "1293143523", r.URL.Query().Get("from"))
+		fmt.Fprint(w, `{
+			"activeprobes": [63, 64],
+			"results": [
+				{
+					"probeid": 63,
+					"time": 1294180323,
+					"status": "down",
+					"responsetime": 0,
+					"statusdesc": "Timeout",
+					"statusdesclong": "Timeout (> 30000 ms)"
+				},
+				{
+					"probeid": 64,
+					"time": 1294180263,
+					"status": "up",
+					"responsetime": 230,
+					"statusdesc": "OK",
+					"statusdesclong": "OK"
+				}
+			]
+		}`)
+	})
+
+	want := []ResultsResponseResult{
+		{
+			ProbeID:        63,
+			Time:           1294180323,
+			Status:         "down",
+			StatusDesc:     "Timeout",
+			StatusDescLong: "Timeout (> 30000 ms)",
+		},
+		{
+			ProbeID:        64,
+			Time:           1294180263,
+			Status:         "up",
+			ResponseTime:   230,
+			StatusDesc:     "OK",
+			StatusDescLong: "OK",
+		},
+	}
+
+	results, err := client.Results.List(1, map[string]string{
+		"from": "1293143523",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, want, results)
+}
@@ -0,0 +1,181 @@
+package pingdom
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Latency SLO tag format, i.e. latency_slo_p95_500ms or latency_slo_99_1s.
+var latencySLORegexp = regexp.MustCompile(`^latency_slo_p?(\d+)_(\d+)(ms|s)$`)
+
+// Sources of the uptime SLO of a check.
+const (
+	UptimeSLOSourceTag     = "tag"
+	UptimeSLOSourceDefault = "default"
+)
+
+// Reasons an SLO tag is invalid.
+const (
+	// The tag starts with "uptime_slo_" or "latency_slo_" but doesn't have
+	// the expected format, i.e. "uptime_slo_99.9".
+	SLOTagErrorUnparseable = "unparseable"
+
+	// The SLO of the tag is out of the valid range, i.e. an uptime SLO of 0
+	// or above 100, or below the minimum.
+	SLOTagErrorOutOfRange = "out_of_range"
+
+	// The check has several valid tags with different uptime SLOs.
+	SLOTagErrorConflicting = "conflicting"
+)
+
+// UptimeSLO is the uptime SLO of a check, as configured via its tags.
+type UptimeSLO struct {
+	// Value is the uptime SLO, in percent.
+	Value float64
+
+	// Source is UptimeSLOSourceTag when the uptime SLO is configured via
+	// Tag, or UptimeSLOSourceDefault.
+	Source string
+	Tag    string
+
+	// Errors are the invalid uptime SLO tags of the check, which are
+	// ignored.
+	Errors []SLOTagError
+}
+
+// SLOTagError is an invalid SLO tag.
+type SLOTagError struct {
+	Tag    string
+	Reason string
+}
+
+func (e SLOTagError) Error() string {
+	return fmt.Sprintf("invalid SLO tag %s: %s", e.Tag, e.Reason)
+}
+
+// UptimeSLO returns the uptime SLO configured to this check via a tag, i.e.
+// "uptime_slo_999" for 99.9 uptime SLO, where the first two digits are the
+// integer part. Tags which can't be parsed or whose uptime SLO is out of the
+// (0, 100] range, or below minUptimeSLO when positive, are ignored. When the
+// check has no valid tag, or several ones with different uptime SLOs, the
+// default uptime SLO is used.
+func (cr *CheckResponse) UptimeSLO(defaultUptimeSLO, minUptimeSLO float64) UptimeSLO {
+	slo := UptimeSLO{Value: defaultUptimeSLO, Source: UptimeSLOSourceDefault}
+
+	var valid []UptimeSLO
+	for _, tag := range cr.Tags {
+		if !strings.HasPrefix(tag.Name, "uptime_slo_") {
+			continue
+		}
+
+		matches := uptimeSLORegexp.FindStringSubmatch(tag.Name)
+		if len(matches) == 0 {
+			slo.Errors = append(slo.Errors, SLOTagError{tag.Name, SLOTagErrorUnparseable})
+			continue
+		}
+
+		n, err := strconv.ParseFloat(matches[1], 64)
+		if err != nil {
+			slo.Errors = append(slo.Errors, SLOTagError{tag.Name, SLOTagErrorUnparseable})
+			continue
+		}
+
+		value := n / math.Pow(10, math.Max(0, float64(len(matches[1])-2)))
+		if value <= 0 || value > 100 || value < minUptimeSLO {
+			slo.Errors = append(slo.Errors, SLOTagError{tag.Name, SLOTagErrorOutOfRange})
+			continue
+		}
+
+		valid = append(valid, UptimeSLO{Value: value, Source: UptimeSLOSourceTag, Tag: tag.Name})
+	}
+
+	if len(valid) == 0 {
+		return slo
+	}
+
+	// Tags configuring the same uptime SLO, i.e. uptime_slo_999 and
+	// uptime_slo_9990, don't conflict
+	for _, other := range valid[1:] {
+		if other.Value != valid[0].Value {
+			tags := make([]string, len(valid))
+			for i, v := range valid {
+				tags[i] = v.Tag
+			}
+			slo.Errors = append(slo.Errors, SLOTagError{strings.Join(tags, ","), SLOTagErrorConflicting})
+			return slo
+		}
+	}
+
+	slo.Value, slo.Source, slo.Tag = valid[0].Value, valid[0].Source, valid[0].Tag
+	return slo
+}
+
+// LatencySLO is a latency SLO of a check: the percentage of its successful
+// tests which respond within the threshold.
+type LatencySLO struct {
+	Tag string
+
+	// Objective is the percentage of tests, i.e. 95 or 99.9.
+	Objective float64
+	Threshold time.Duration
+}
+
+// LatencySLOs returns the latency SLOs configured to this check via tags,
+// i.e. "latency_slo_p95_500ms" or "latency_slo_99_1s" for 95% of the tests
+// responding within 500ms or 99% within 1s. The objective is parsed as the
+// uptime SLO of "uptime_slo_xxx" tags, so "latency_slo_p999_2s" is 99.9%
+// within 2s. Tags which can't be parsed or have a zero objective or
+// threshold are returned as errors, and tags with the same objective and
+// threshold as a previous one are ignored.
+func (cr *CheckResponse) LatencySLOs() ([]LatencySLO, []SLOTagError) {
+	var (
+		slos []LatencySLO
+		errs []SLOTagError
+	)
+
+	for _, tag := range cr.Tags {
+		if !strings.HasPrefix(tag.Name, "latency_slo_") {
+			continue
+		}
+
+		matches := latencySLORegexp.FindStringSubmatch(tag.Name)
+		if len(matches) == 0 {
+			errs = append(errs, SLOTagError{tag.Name, SLOTagErrorUnparseable})
+			continue
+		}
+
+		objective, err1 := strconv.ParseFloat(matches[1], 64)
+		threshold, err2 := strconv.ParseInt(matches[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			errs = append(errs, SLOTagError{tag.Name, SLOTagErrorUnparseable})
+			continue
+		}
+
+		slo := LatencySLO{
+			Tag:       tag.Name,
+			Objective: objective / math.Pow(10, math.Max(0, float64(len(matches[1])-2))),
+			Threshold: time.Duration(threshold) * time.Millisecond,
+		}
+		if matches[3] == "s" {
+			slo.Threshold = time.Duration(threshold) * time.Second
+		}
+
+		if slo.Objective <= 0 || slo.Threshold <= 0 {
+			errs = append(errs, SLOTagError{tag.Name, SLOTagErrorOutOfRange})
+			continue
+		}
+
+		if !slices.ContainsFunc(slos, func(other LatencySLO) bool {
+			return other.Objective == slo.Objective && other.Threshold == slo.Threshold
+		}) {
+			slos = append(slos, slo)
+		}
+	}
+
+	return slos, errs
+}
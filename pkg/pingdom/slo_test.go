@@ -0,0 +1,103 @@
+package pingdom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResponseUptimeSLO(t *testing.T) {
+	testCases := []struct {
+		tags     []string
+		expected UptimeSLO
+	}{
+		{
+			tags:     []string{"payments", "uptime_slo_9995"},
+			expected: UptimeSLO{Value: 99.95, Source: UptimeSLOSourceTag, Tag: "uptime_slo_9995"},
+		},
+		{
+			tags:     []string{"payments"},
+			expected: UptimeSLO{Value: 99, Source: UptimeSLOSourceDefault},
+		},
+		{
+			// Equal uptime SLOs don't conflict
+			tags:     []string{"uptime_slo_999", "uptime_slo_9990"},
+			expected: UptimeSLO{Value: 99.9, Source: UptimeSLOSourceTag, Tag: "uptime_slo_999"},
+		},
+		{
+			tags: []string{"uptime_slo_9"},
+			expected: UptimeSLO{Value: 99, Source: UptimeSLOSourceDefault, Errors: []SLOTagError{
+				{"uptime_slo_9", SLOTagErrorOutOfRange},
+			}},
+		},
+		{
+			// The first two digits are the integer part, so this is 10%
+			tags: []string{"uptime_slo_100"},
+			expected: UptimeSLO{Value: 99, Source: UptimeSLOSourceDefault, Errors: []SLOTagError{
+				{"uptime_slo_100", SLOTagErrorOutOfRange},
+			}},
+		},
+		{
+			// Invalid tags are ignored
+			tags: []string{"uptime_slo_99.9", "uptime_slo_995"},
+			expected: UptimeSLO{Value: 99.5, Source: UptimeSLOSourceTag, Tag: "uptime_slo_995", Errors: []SLOTagError{
+				{"uptime_slo_99.9", SLOTagErrorUnparseable},
+			}},
+		},
+		{
+			tags: []string{"uptime_slo_999", "uptime_slo_99", "uptime_slo_"},
+			expected: UptimeSLO{Value: 99, Source: UptimeSLOSourceDefault, Errors: []SLOTagError{
+				{"uptime_slo_", SLOTagErrorUnparseable},
+				{"uptime_slo_999,uptime_slo_99", SLOTagErrorConflicting},
+			}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		response := CheckResponse{}
+		for _, tag := range testCase.tags {
+			response.Tags = append(response.Tags, CheckResponseTag{Name: tag})
+		}
+
+		assert.Equal(t, testCase.expected, response.UptimeSLO(99, 90), "%v", testCase.tags)
+	}
+
+	// The minimum uptime SLO is opt-in, while an uptime SLO of 0 is always
+	// out of range
+	response := CheckResponse{Tags: []CheckResponseTag{{Name: "uptime_slo_9"}}}
+	assert.Equal(t, 9.0, response.UptimeSLO(99, 0).Value)
+
+	response.Tags[0].Name = "uptime_slo_000"
+	assert.Equal(t, UptimeSLO{Value: 99, Source: UptimeSLOSourceDefault, Errors: []SLOTagError{
+		{"uptime_slo_000", SLOTagErrorOutOfRange},
+	}}, response.UptimeSLO(99, 0))
+
+	assert.EqualError(t, SLOTagError{"uptime_slo_9", SLOTagErrorOutOfRange}, "invalid SLO tag uptime_slo_9: out_of_range")
+}
+
+func TestCheckResponseLatencySLOs(t *testing.T) {
+	response := CheckResponse{}
+	for _, tag := range []string{
+		"payments",
+		"latency_slo_p95_500ms",
+		"latency_slo_999_2s",
+		"latency_slo_95_500ms",
+		"latency_slo_p99_1.5s",
+		"latency_slo_p0_1s",
+		"latency_slo_p99_0ms",
+	} {
+		response.Tags = append(response.Tags, CheckResponseTag{Name: tag})
+	}
+
+	slos, errs := response.LatencySLOs()
+	assert.Equal(t, []LatencySLO{
+		{Tag: "latency_slo_p95_500ms", Objective: 95, Threshold: 500 * time.Millisecond},
+		{Tag: "latency_slo_999_2s", Objective: 99.9, Threshold: 2 * time.Second},
+	}, slos)
+	assert.Equal(t, []SLOTagError{
+		{"latency_slo_p99_1.5s", SLOTagErrorUnparseable},
+		{"latency_slo_p0_1s", SLOTagErrorOutOfRange},
+		{"latency_slo_p99_0ms", SLOTagErrorOutOfRange},
+	}, errs)
+}